@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/naoina/kocha/util"
+)
+
+const (
+	versionFormat = "20060102150405"
+	defaultDriver = "sqlite3"
+)
+
+// primaryKeyMap is a map of driver names to the definitions of the
+// auto-increment primary key column.
+var primaryKeyMap = map[string]string{
+	"mysql":    "id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY",
+	"postgres": "id BIGSERIAL PRIMARY KEY",
+	"sqlite3":  "id INTEGER PRIMARY KEY AUTOINCREMENT",
+}
+
+// timestampColumns are the columns that are added to the created table for
+// the timestamps of the records. (e.g. genmai.TimeStamp)
+var timestampColumns = []string{
+	"created_at TIMESTAMP",
+	"updated_at TIMESTAMP",
+}
+
+var sqlTypeMap = map[string]string{
+	"int":        "INTEGER",
+	"integer":    "INTEGER",
+	"int8":       "SMALLINT",
+	"byte":       "SMALLINT",
+	"int16":      "SMALLINT",
+	"smallint":   "SMALLINT",
+	"int32":      "INTEGER",
+	"int64":      "BIGINT",
+	"bigint":     "BIGINT",
+	"string":     "VARCHAR(255)",
+	"text":       "TEXT",
+	"mediumtext": "TEXT",
+	"longtext":   "TEXT",
+	"bytea":      "BLOB",
+	"blob":       "BLOB",
+	"mediumblob": "BLOB",
+	"longblob":   "BLOB",
+	"bool":       "BOOLEAN",
+	"boolean":    "BOOLEAN",
+	"float":      "DOUBLE PRECISION",
+	"float64":    "DOUBLE PRECISION",
+	"double":     "DOUBLE PRECISION",
+	"real":       "DOUBLE PRECISION",
+	"date":       "DATE",
+	"time":       "TIME",
+	"datetime":   "TIMESTAMP",
+	"timestamp":  "TIMESTAMP",
+	"decimal":    "DECIMAL",
+	"numeric":    "DECIMAL",
+}
+
+type generateMigrationCommand struct {
+	option struct {
+		Driver string `short:"d" long:"driver"`
+		Help   bool   `short:"h" long:"help"`
+	}
+}
+
+func (c *generateMigrationCommand) Name() string {
+	return "kocha generate migration"
+}
+
+func (c *generateMigrationCommand) Usage() string {
+	return fmt.Sprintf(`Usage: %s [OPTIONS] NAME [[field:type]...]
+
+Generate the skeleton files of migration.
+
+The column operations will be inferred from NAME if it follows the
+conventions below:
+
+    create_TABLE                      create TABLE
+    drop_TABLE                        drop TABLE
+    add_COLUMN[_and_COLUMN...]_to_TABLE
+                                      add COLUMNs to TABLE
+    remove_COLUMN[_and_COLUMN...]_from_TABLE
+                                      remove COLUMNs from TABLE
+
+The types of the columns are given by field:type arguments.
+If they aren't given, the column names are taken from NAME and their types
+are "string".
+
+Options:
+    -d, --driver=DRIVER
+                      database driver of the generated queries [default: "%s"]
+                      available drivers: %s
+    -h, --help        display this help and exit
+
+`, c.Name(), defaultDriver, strings.Join(driverNames(), ", "))
+}
+
+func (c *generateMigrationCommand) Option() interface{} {
+	return &c.option
+}
+
+// Run generates migration templates.
+func (c *generateMigrationCommand) Run(args []string) error {
+	if len(args) < 1 || args[0] == "" {
+		return fmt.Errorf("no NAME given")
+	}
+	name := util.ToSnakeCase(args[0])
+	if c.option.Driver == "" {
+		c.option.Driver = defaultDriver
+	}
+	if _, found := primaryKeyMap[c.option.Driver]; !found {
+		return fmt.Errorf("unsupported driver: `%v'", c.option.Driver)
+	}
+	fields, err := parseFields(args[1:])
+	if err != nil {
+		return err
+	}
+	up, down := inferQueries(c.option.Driver, name, fields)
+	version := util.Now().Format(versionFormat)
+	data := map[string]interface{}{
+		"Name":        util.ToCamelCase(name),
+		"Version":     version,
+		"UpQueries":   up,
+		"DownQueries": down,
+	}
+	if err := util.CopyTemplate(
+		filepath.Join(skeletonDir("migration"), "migration.go"+util.TemplateSuffix),
+		filepath.Join("db", "migration", version+"_"+name+".go"), data); err != nil {
+		return err
+	}
+	initPath := filepath.Join("db", "migration", "init.go")
+	if _, err := os.Stat(initPath); os.IsNotExist(err) {
+		if err := util.CopyTemplate(
+			filepath.Join(skeletonDir("migration"), "init.go"+util.TemplateSuffix),
+			initPath, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type migrationField struct {
+	Column string
+	Type   string
+}
+
+func parseFields(args []string) ([]migrationField, error) {
+	var fields []migrationField
+	for _, arg := range args {
+		input := strings.Split(arg, ":")
+		if len(input) != 2 {
+			return nil, fmt.Errorf("invalid argument format is specified: `%v'", arg)
+		}
+		name, t := input[0], input[1]
+		if name == "" {
+			return nil, fmt.Errorf("field name isn't specified: `%v'", arg)
+		}
+		if t == "" {
+			return nil, fmt.Errorf("field type isn't specified: `%v'", arg)
+		}
+		sqlType, found := sqlTypeMap[t]
+		if !found {
+			return nil, fmt.Errorf("unsupported field type: `%v'", t)
+		}
+		fields = append(fields, migrationField{
+			Column: util.ToSnakeCase(name),
+			Type:   sqlType,
+		})
+	}
+	return fields, nil
+}
+
+// inferQueries returns the queries for Up and Down that inferred from the
+// name of the migration.
+// If the name doesn't follow any conventions, it returns nil.
+func inferQueries(driver, name string, fields []migrationField) (up, down []string) {
+	switch {
+	case strings.HasPrefix(name, "create_"):
+		table := strings.TrimPrefix(name, "create_")
+		return []string{createTableQuery(driver, table, fields)}, []string{dropTableQuery(table)}
+	case strings.HasPrefix(name, "drop_"):
+		table := strings.TrimPrefix(name, "drop_")
+		return []string{dropTableQuery(table)}, []string{createTableQuery(driver, table, fields)}
+	case strings.HasPrefix(name, "add_") && strings.Contains(name, "_to_"):
+		columns, table := splitColumnsAndTable(strings.TrimPrefix(name, "add_"), "_to_")
+		if table == "" {
+			break
+		}
+		fields = fieldsOrColumns(fields, columns)
+		return addColumnQueries(table, fields), dropColumnQueries(table, fields)
+	case strings.HasPrefix(name, "remove_") && strings.Contains(name, "_from_"):
+		columns, table := splitColumnsAndTable(strings.TrimPrefix(name, "remove_"), "_from_")
+		if table == "" {
+			break
+		}
+		fields = fieldsOrColumns(fields, columns)
+		return dropColumnQueries(table, fields), addColumnQueries(table, fields)
+	}
+	return nil, nil
+}
+
+func splitColumnsAndTable(s, sep string) (columns []string, table string) {
+	i := strings.LastIndex(s, sep)
+	if i < 1 {
+		return nil, ""
+	}
+	return strings.Split(s[:i], "_and_"), s[i+len(sep):]
+}
+
+func fieldsOrColumns(fields []migrationField, columns []string) []migrationField {
+	if len(fields) > 0 {
+		return fields
+	}
+	for _, column := range columns {
+		fields = append(fields, migrationField{
+			Column: column,
+			Type:   sqlTypeMap["string"],
+		})
+	}
+	return fields
+}
+
+func createTableQuery(driver, table string, fields []migrationField) string {
+	columns := []string{primaryKeyMap[driver]}
+	for _, field := range fields {
+		columns = append(columns, field.Column+" "+field.Type)
+	}
+	columns = append(columns, timestampColumns...)
+	return fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", "))
+}
+
+func dropTableQuery(table string) string {
+	return fmt.Sprintf("DROP TABLE %s", table)
+}
+
+func addColumnQueries(table string, fields []migrationField) []string {
+	queries := make([]string, len(fields))
+	for i, field := range fields {
+		queries[i] = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, field.Column, field.Type)
+	}
+	return queries
+}
+
+func dropColumnQueries(table string, fields []migrationField) []string {
+	queries := make([]string, len(fields))
+	for i := range fields {
+		// drop the columns in the reverse order of addition.
+		queries[i] = fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, fields[len(fields)-1-i].Column)
+	}
+	return queries
+}
+
+func driverNames() []string {
+	names := make([]string, 0, len(primaryKeyMap))
+	for name := range primaryKeyMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func skeletonDir(name string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	baseDir := filepath.Dir(filename)
+	return filepath.Join(baseDir, "skeleton", name)
+}
+
+func main() {
+	util.RunCommand(&generateMigrationCommand{})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naoina/kocha/util"
+)
+
+func Test_generateMigrationCommand_Run(t *testing.T) {
+	// test for no arguments.
+	func() {
+		c := &generateMigrationCommand{}
+		args := []string{}
+		err := c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = fmt.Errorf("no NAME given")
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+	}()
+
+	// test for unsupported driver.
+	func() {
+		c := &generateMigrationCommand{}
+		c.option.Driver = "unknown"
+		args := []string{"create_users"}
+		err := c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = fmt.Errorf("unsupported driver: `unknown'")
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+	}()
+
+	// test for invalid argument formats.
+	func() {
+		c := &generateMigrationCommand{}
+		for _, v := range []struct {
+			arg    string
+			expect interface{}
+		}{
+			{"field", fmt.Errorf("invalid argument format is specified: `field'")},
+			{":", fmt.Errorf("field name isn't specified: `:'")},
+			{"field:", fmt.Errorf("field type isn't specified: `field:'")},
+			{"field:unknown", fmt.Errorf("unsupported field type: `unknown'")},
+		} {
+			args := []string{"create_users", v.arg}
+			err := c.Run(args)
+			var actual interface{} = err
+			var expect interface{} = v.expect
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+			}
+		}
+	}()
+
+	func() {
+		tempdir, err := ioutil.TempDir("", "TestGenerateMigration")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tempdir)
+		os.Chdir(tempdir)
+		f, err := os.OpenFile(os.DevNull, os.O_WRONLY, os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		oldStdout, oldStderr := os.Stdout, os.Stderr
+		os.Stdout, os.Stderr = f, f
+		defer func() {
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+		}()
+		oldNow := util.Now
+		util.Now = func() time.Time {
+			return time.Date(2014, 10, 26, 12, 34, 56, 0, time.UTC)
+		}
+		defer func() {
+			util.Now = oldNow
+		}()
+		c := &generateMigrationCommand{}
+		args := []string{"add_email_to_users"}
+		err = c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = nil
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+
+		path := filepath.Join("db", "migration", "20141026123456_add_email_to_users.go")
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("generate(%#v); file %#v is not exists; want exists", args, path)
+		}
+		for _, s := range []string{
+			"func (m *Migration) Up_20141026123456_AddEmailToUsers(tx *sql.Tx) error {",
+			"func (m *Migration) Down_20141026123456_AddEmailToUsers(tx *sql.Tx) error {",
+			`"ALTER TABLE users ADD COLUMN email VARCHAR(255)"`,
+			`"ALTER TABLE users DROP COLUMN email"`,
+		} {
+			if !strings.Contains(string(buf), s) {
+				t.Errorf("generate(%#v); %#v doesn't contain %#v", args, path, s)
+			}
+		}
+		path = filepath.Join("db", "migration", "init.go")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			t.Errorf("generate(%#v); file %#v is not exists; want exists", args, path)
+		}
+	}()
+}
+
+func Test_inferQueries(t *testing.T) {
+	for _, v := range []struct {
+		name       string
+		fields     []migrationField
+		expectUp   []string
+		expectDown []string
+	}{
+		{"create_users", []migrationField{{"name", "VARCHAR(255)"}, {"age", "INTEGER"}},
+			[]string{"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255), age INTEGER, created_at TIMESTAMP, updated_at TIMESTAMP)"},
+			[]string{"DROP TABLE users"}},
+		{"drop_users", nil,
+			[]string{"DROP TABLE users"},
+			[]string{"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, created_at TIMESTAMP, updated_at TIMESTAMP)"}},
+		{"add_email_to_users", nil,
+			[]string{"ALTER TABLE users ADD COLUMN email VARCHAR(255)"},
+			[]string{"ALTER TABLE users DROP COLUMN email"}},
+		{"add_email_and_age_to_users", []migrationField{{"email", "TEXT"}, {"age", "INTEGER"}},
+			[]string{"ALTER TABLE users ADD COLUMN email TEXT", "ALTER TABLE users ADD COLUMN age INTEGER"},
+			[]string{"ALTER TABLE users DROP COLUMN age", "ALTER TABLE users DROP COLUMN email"}},
+		{"remove_email_from_users", []migrationField{{"email", "TEXT"}},
+			[]string{"ALTER TABLE users DROP COLUMN email"},
+			[]string{"ALTER TABLE users ADD COLUMN email TEXT"}},
+		{"add_to_users", nil, nil, nil},
+		{"fix_something", nil, nil, nil},
+	} {
+		up, down := inferQueries("sqlite3", v.name, v.fields)
+		if !reflect.DeepEqual(up, v.expectUp) || !reflect.DeepEqual(down, v.expectDown) {
+			t.Errorf(`inferQueries(%#v, %#v) => (%#v, %#v); want (%#v, %#v)`, v.name, v.fields, up, down, v.expectUp, v.expectDown)
+		}
+	}
+}
+
+func Test_createTableQuery(t *testing.T) {
+	fields := []migrationField{{"name", "VARCHAR(255)"}}
+	for _, v := range []struct {
+		driver string
+		expect string
+	}{
+		{"mysql", "CREATE TABLE users (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, name VARCHAR(255), created_at TIMESTAMP, updated_at TIMESTAMP)"},
+		{"postgres", "CREATE TABLE users (id BIGSERIAL PRIMARY KEY, name VARCHAR(255), created_at TIMESTAMP, updated_at TIMESTAMP)"},
+		{"sqlite3", "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255), created_at TIMESTAMP, updated_at TIMESTAMP)"},
+	} {
+		actual := createTableQuery(v.driver, "users", fields)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`createTableQuery(%#v, %#v, %#v) => %#v; want %#v`, v.driver, "users", fields, actual, expect)
+		}
+	}
+}
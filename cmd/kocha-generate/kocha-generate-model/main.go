@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/naoina/kocha/util"
@@ -15,6 +16,22 @@ const (
 
 var modelTypeMap = map[string]ModelTyper{
 	"genmai": &GenmaiModelType{},
+	"sql":    &SQLModelType{},
+	"sqlx":   &SQLXModelType{},
+}
+
+// fieldsRequiredORMs is a set of the ORMs that require at least one field,
+// because the queries of their models list the columns of the fields.
+var fieldsRequiredORMs = map[string]bool{
+	"sql":  true,
+	"sqlx": true,
+}
+
+// importPathMap is a map of package names to import paths of the packages
+// that are used by the field types.
+var importPathMap = map[string]string{
+	"time":  "time",
+	"types": "github.com/jmoiron/sqlx/types",
 }
 
 type generateModelCommand struct {
@@ -33,9 +50,10 @@ func (c *generateModelCommand) Usage() string {
 
 Options:
     -o, --orm=ORM     ORM to be used for a model [default: "%s"]
+                      available ORMs: %s
     -h, --help        display this help and exit
 
-`, c.Name(), defaultORM)
+`, c.Name(), defaultORM, strings.Join(ormNames(), ", "))
 }
 
 func (c *generateModelCommand) Option() interface{} {
@@ -80,11 +98,16 @@ func (c *generateModelCommand) Run(args []string) error {
 			OptionTags: ft.OptionTags,
 		})
 	}
+	if len(fields) == 0 && fieldsRequiredORMs[c.option.ORM] {
+		return fmt.Errorf("no fields given: ORM `%v' requires at least one field", c.option.ORM)
+	}
 	camelCaseName := util.ToCamelCase(name)
 	snakeCaseName := util.ToSnakeCase(name)
 	data := map[string]interface{}{
-		"Name":   camelCaseName,
-		"Fields": fields,
+		"Name":    camelCaseName,
+		"Table":   snakeCaseName,
+		"Fields":  fields,
+		"Imports": importPaths(fields),
 	}
 	templatePath, configTemplatePath := mt.TemplatePath()
 	if err := util.CopyTemplate(templatePath, filepath.Join("app", "model", snakeCaseName+".go"), data); err != nil {
@@ -99,6 +122,35 @@ func (c *generateModelCommand) Run(args []string) error {
 	return nil
 }
 
+// importPaths returns the sorted import paths of the packages that are used
+// by the types of fields.
+func importPaths(fields []modelField) []string {
+	m := make(map[string]struct{})
+	for _, field := range fields {
+		t := strings.TrimLeft(field.Type, "[]*")
+		if i := strings.Index(t, "."); i >= 0 {
+			if path, found := importPathMap[t[:i]]; found {
+				m[path] = struct{}{}
+			}
+		}
+	}
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func ormNames() []string {
+	names := make([]string, 0, len(modelTypeMap))
+	for name := range modelTypeMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 type modelField struct {
 	Name       string
 	Type       string
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -35,6 +36,19 @@ func Test_generateModelCommand_Run(t *testing.T) {
 		}
 	}()
 
+	// test for no fields with the ORMs that require them.
+	for _, orm := range []string{"sql", "sqlx"} {
+		c := &generateModelCommand{}
+		c.option.ORM = orm
+		args := []string{"app_model"}
+		err := c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = fmt.Errorf("no fields given: ORM `%v' requires at least one field", orm)
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) with %#v => %#v; want %#v`, args, orm, actual, expect)
+		}
+	}
+
 	// test for invalid argument formats.
 	func() {
 		c := &generateModelCommand{}
@@ -94,4 +108,53 @@ func Test_generateModelCommand_Run(t *testing.T) {
 			t.Errorf("generate(%#v); file %#v is not exists; want exists", args, expect)
 		}
 	}()
+
+	// test for other ORMs.
+	for _, orm := range []string{"sql", "sqlx"} {
+		func() {
+			tempdir, err := ioutil.TempDir("", "TestModelGeneratorGenerate")
+			if err != nil {
+				panic(err)
+			}
+			defer os.RemoveAll(tempdir)
+			os.Chdir(tempdir)
+			f, err := os.OpenFile(os.DevNull, os.O_WRONLY, os.ModePerm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			os.Stdout, os.Stderr = f, f
+			defer func() {
+				os.Stdout, os.Stderr = oldStdout, oldStderr
+			}()
+			c := &generateModelCommand{}
+			c.option.ORM = orm
+			args := []string{"app_model", "name:string", "born_at:datetime"}
+			err = c.Run(args)
+			var actual interface{} = err
+			var expect interface{} = nil
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf(`generate(%#v) with %#v => %#v; want %#v`, args, orm, actual, expect)
+			}
+
+			for _, path := range []string{
+				filepath.Join("app", "model", "app_model.go"),
+				filepath.Join("db", "config.go"),
+			} {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					t.Errorf("generate(%#v) with %#v; file %#v is not exists; want exists", args, orm, path)
+				}
+			}
+			buf, err := ioutil.ReadFile(filepath.Join("app", "model", "app_model.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = strings.Contains(string(buf), "SELECT id, name, born_at FROM app_model ORDER BY id")
+			expect = true
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf("generate(%#v) with %#v; query of FindAll => %#v; want %#v", args, orm, actual, expect)
+			}
+		}()
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/naoina/kocha/util"
+)
+
+// SQLModelType implements ModelTyper interface.
+type SQLModelType struct{}
+
+// sqlFieldTypeMap is the type map for database/sql.
+// It's also the base of sqlxFieldTypeMap.
+var sqlFieldTypeMap = map[string]ModelFieldType{
+	"int":        ModelFieldType{"int", nil},
+	"integer":    ModelFieldType{"int", nil},
+	"int8":       ModelFieldType{"int8", nil},
+	"byte":       ModelFieldType{"int8", nil},
+	"int16":      ModelFieldType{"int16", nil},
+	"smallint":   ModelFieldType{"int16", nil},
+	"int32":      ModelFieldType{"int32", nil},
+	"int64":      ModelFieldType{"int64", nil},
+	"bigint":     ModelFieldType{"int64", nil},
+	"string":     ModelFieldType{"string", nil},
+	"text":       ModelFieldType{"string", nil},
+	"mediumtext": ModelFieldType{"string", nil},
+	"longtext":   ModelFieldType{"string", nil},
+	"bytea":      ModelFieldType{"[]byte", nil},
+	"blob":       ModelFieldType{"[]byte", nil},
+	"mediumblob": ModelFieldType{"[]byte", nil},
+	"longblob":   ModelFieldType{"[]byte", nil},
+	"bool":       ModelFieldType{"bool", nil},
+	"boolean":    ModelFieldType{"bool", nil},
+	"float":      ModelFieldType{"float64", nil},
+	"float64":    ModelFieldType{"float64", nil},
+	"double":     ModelFieldType{"float64", nil},
+	"real":       ModelFieldType{"float64", nil},
+	"date":       ModelFieldType{"time.Time", nil},
+	"time":       ModelFieldType{"time.Time", nil},
+	"datetime":   ModelFieldType{"time.Time", nil},
+	"timestamp":  ModelFieldType{"time.Time", nil},
+	"decimal":    ModelFieldType{"string", nil},
+	"numeric":    ModelFieldType{"string", nil},
+}
+
+// FieldTypeMap returns type map for database/sql.
+func (mt *SQLModelType) FieldTypeMap() map[string]ModelFieldType {
+	return sqlFieldTypeMap
+}
+
+// TemplatePath returns paths that templates of database/sql for model generation.
+func (mt *SQLModelType) TemplatePath() (templatePath string, configTemplatePath string) {
+	templatePath = filepath.Join(skeletonDir("model"), "sql", "sql.go"+util.TemplateSuffix)
+	configTemplatePath = filepath.Join(skeletonDir("model"), "sql", "config.go"+util.TemplateSuffix)
+	return templatePath, configTemplatePath
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/naoina/kocha/util"
+)
+
+func TestSQLModelTypes_FieldTypeMap(t *testing.T) {
+	m := map[string]ModelFieldType{
+		"int":        ModelFieldType{"int", nil},
+		"integer":    ModelFieldType{"int", nil},
+		"int8":       ModelFieldType{"int8", nil},
+		"byte":       ModelFieldType{"int8", nil},
+		"int16":      ModelFieldType{"int16", nil},
+		"smallint":   ModelFieldType{"int16", nil},
+		"int32":      ModelFieldType{"int32", nil},
+		"int64":      ModelFieldType{"int64", nil},
+		"bigint":     ModelFieldType{"int64", nil},
+		"string":     ModelFieldType{"string", nil},
+		"text":       ModelFieldType{"string", nil},
+		"mediumtext": ModelFieldType{"string", nil},
+		"longtext":   ModelFieldType{"string", nil},
+		"bytea":      ModelFieldType{"[]byte", nil},
+		"blob":       ModelFieldType{"[]byte", nil},
+		"mediumblob": ModelFieldType{"[]byte", nil},
+		"longblob":   ModelFieldType{"[]byte", nil},
+		"bool":       ModelFieldType{"bool", nil},
+		"boolean":    ModelFieldType{"bool", nil},
+		"float":      ModelFieldType{"float64", nil},
+		"float64":    ModelFieldType{"float64", nil},
+		"double":     ModelFieldType{"float64", nil},
+		"real":       ModelFieldType{"float64", nil},
+		"date":       ModelFieldType{"time.Time", nil},
+		"time":       ModelFieldType{"time.Time", nil},
+		"datetime":   ModelFieldType{"time.Time", nil},
+		"timestamp":  ModelFieldType{"time.Time", nil},
+		"decimal":    ModelFieldType{"string", nil},
+		"numeric":    ModelFieldType{"string", nil},
+	}
+	sqlxm := map[string]ModelFieldType{
+		"json":  ModelFieldType{"types.JSONText", nil},
+		"jsonb": ModelFieldType{"types.JSONText", nil},
+		"bit":   ModelFieldType{"types.BitBool", nil},
+	}
+	for name, ft := range m {
+		sqlxm[name] = ft
+	}
+	for _, v := range []struct {
+		mt     ModelTyper
+		expect map[string]ModelFieldType
+	}{
+		{&SQLModelType{}, m},
+		{&SQLXModelType{}, sqlxm},
+	} {
+		actual := v.mt.FieldTypeMap()
+		expected := v.expect
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("(%T).FieldTypeMap() => %#v, want %#v", v.mt, actual, expected)
+		}
+	}
+}
+
+func TestSQLModelTypes_TemplatePath(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	basepath := filepath.Dir(filename)
+	for _, v := range []struct {
+		mt   ModelTyper
+		name string
+	}{
+		{&SQLModelType{}, "sql"},
+		{&SQLXModelType{}, "sqlx"},
+	} {
+		path1, path2 := v.mt.TemplatePath()
+		actual := path1
+		expected := filepath.Join(basepath, "skeleton", "model", v.name, v.name+".go"+util.TemplateSuffix)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("(%T).TemplatePath() => %#v, $, want %#v, $", v.mt, actual, expected)
+		}
+		actual = path2
+		expected = filepath.Join(basepath, "skeleton", "model", v.name, "config.go"+util.TemplateSuffix)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("(%T).TemplatePath() => $, %#v, want $, %#v", v.mt, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/naoina/kocha/util"
+)
+
+// SQLXModelType implements ModelTyper interface.
+type SQLXModelType struct{}
+
+// sqlxFieldTypeMap is the type map for sqlx.
+// It consists of sqlFieldTypeMap and the types of sqlx/types package.
+var sqlxFieldTypeMap = func() map[string]ModelFieldType {
+	m := map[string]ModelFieldType{
+		"json":  ModelFieldType{"types.JSONText", nil},
+		"jsonb": ModelFieldType{"types.JSONText", nil},
+		"bit":   ModelFieldType{"types.BitBool", nil},
+	}
+	for name, t := range sqlFieldTypeMap {
+		m[name] = t
+	}
+	return m
+}()
+
+// FieldTypeMap returns type map for sqlx.
+func (mt *SQLXModelType) FieldTypeMap() map[string]ModelFieldType {
+	return sqlxFieldTypeMap
+}
+
+// TemplatePath returns paths that templates of sqlx for model generation.
+func (mt *SQLXModelType) TemplatePath() (templatePath string, configTemplatePath string) {
+	templatePath = filepath.Join(skeletonDir("model"), "sqlx", "sqlx.go"+util.TemplateSuffix)
+	configTemplatePath = filepath.Join(skeletonDir("model"), "sqlx", "config.go"+util.TemplateSuffix)
+	return templatePath, configTemplatePath
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/kocha/util"
+)
+
+const (
+	defaultORM = "genmai"
+)
+
+// controllerTemplateMap is a map of ORM names to names of the controller
+// templates. The models of "sql" and "sqlx" have the same API.
+var controllerTemplateMap = map[string]string{
+	"genmai": "genmai",
+	"sql":    "sql",
+	"sqlx":   "sql",
+}
+
+// generateModel generates the model by kocha-generate-model.
+// This is a variable for testing.
+var generateModel = func(args []string) error {
+	filename, err := exec.LookPath("kocha-generate-model")
+	if err != nil {
+		return fmt.Errorf("could not found generator: model")
+	}
+	cmd := exec.Command(filename, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+type generateScaffoldCommand struct {
+	option struct {
+		ORM  string `short:"o" long:"orm"`
+		Help bool   `short:"h" long:"help"`
+	}
+}
+
+func (c *generateScaffoldCommand) Name() string {
+	return "kocha generate scaffold"
+}
+
+func (c *generateScaffoldCommand) Usage() string {
+	return fmt.Sprintf(`Usage: %s [OPTIONS] NAME [[field:type]...]
+
+Generate the model, controllers and views for CRUD of NAME.
+
+Options:
+    -o, --orm=ORM     ORM to be used for a model [default: "%s"]
+    -h, --help        display this help and exit
+
+`, c.Name(), defaultORM)
+}
+
+func (c *generateScaffoldCommand) Option() interface{} {
+	return &c.option
+}
+
+// Run generates the scaffold files.
+func (c *generateScaffoldCommand) Run(args []string) error {
+	if len(args) < 1 || args[0] == "" {
+		return fmt.Errorf("no NAME given")
+	}
+	name := args[0]
+	if c.option.ORM == "" {
+		c.option.ORM = defaultORM
+	}
+	tmplName, found := controllerTemplateMap[c.option.ORM]
+	if !found {
+		return fmt.Errorf("unsupported ORM: `%v'", c.option.ORM)
+	}
+	controllerTemplatePath := filepath.Join(skeletonDir("scaffold"), "controller", tmplName+".go"+util.TemplateSuffix)
+	fields, err := parseFields(args[1:])
+	if err != nil {
+		return err
+	}
+	appDir, err := util.FindAppDir()
+	if err != nil {
+		return err
+	}
+	if err := generateModel(append([]string{"--orm", c.option.ORM}, args...)); err != nil {
+		return err
+	}
+	camelCaseName := util.ToCamelCase(name)
+	snakeCaseName := util.ToSnakeCase(name)
+	data := map[string]interface{}{
+		"AppDir":      appDir,
+		"Name":        camelCaseName,
+		"Plural":      util.ToCamelCase(pluralize(snakeCaseName)),
+		"Var":         lowerFirst(camelCaseName),
+		"PluralVar":   lowerFirst(util.ToCamelCase(pluralize(snakeCaseName))),
+		"Snake":       snakeCaseName,
+		"PluralSnake": pluralize(snakeCaseName),
+		"Fields":      fields,
+		"ParamNames":  paramNames(fields),
+	}
+	if err := util.CopyTemplate(controllerTemplatePath, filepath.Join("app", "controller", snakeCaseName+".go"), data); err != nil {
+		return err
+	}
+	for _, v := range []struct {
+		template string
+		name     string
+	}{
+		{"index", pluralize(snakeCaseName)},
+		{"show", snakeCaseName},
+		{"new", "new_" + snakeCaseName},
+		{"edit", "edit_" + snakeCaseName},
+	} {
+		if err := util.CopyTemplate(
+			filepath.Join(skeletonDir("scaffold"), "view", v.template+".html"+util.TemplateSuffix),
+			filepath.Join("app", "view", v.name+".html"+util.TemplateSuffix), data); err != nil {
+			return err
+		}
+	}
+	printRoutes(camelCaseName, snakeCaseName)
+	return nil
+}
+
+type scaffoldField struct {
+	Name      string // name of the struct field.
+	Param     string // name of the form parameter.
+	InputType string // type attribute of the input element. "textarea" is for the textarea element.
+}
+
+func parseFields(args []string) ([]scaffoldField, error) {
+	var fields []scaffoldField
+	for _, arg := range args {
+		input := strings.Split(arg, ":")
+		if len(input) != 2 {
+			return nil, fmt.Errorf("invalid argument format is specified: `%v'", arg)
+		}
+		name, t := input[0], input[1]
+		if name == "" {
+			return nil, fmt.Errorf("field name isn't specified: `%v'", arg)
+		}
+		if t == "" {
+			return nil, fmt.Errorf("field type isn't specified: `%v'", arg)
+		}
+		fields = append(fields, scaffoldField{
+			Name:      util.ToCamelCase(name),
+			Param:     util.ToSnakeCase(name),
+			InputType: inputType(t),
+		})
+	}
+	return fields, nil
+}
+
+func inputType(t string) string {
+	switch t {
+	case "int", "integer", "int8", "byte", "int16", "smallint", "int32", "int64", "bigint",
+		"float", "float64", "double", "real", "decimal", "numeric":
+		return "number"
+	case "bool", "boolean":
+		return "checkbox"
+	case "text", "mediumtext", "longtext":
+		return "textarea"
+	case "date":
+		return "date"
+	case "time":
+		return "time"
+	case "datetime", "timestamp":
+		return "datetime-local"
+	}
+	return "text"
+}
+
+func paramNames(fields []scaffoldField) string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = strconv.Quote(field.Param)
+	}
+	return strings.Join(names, ", ")
+}
+
+// pluralize returns the plural form of the English noun s.
+func pluralize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func printRoutes(camelCaseName, snakeCaseName string) {
+	plural := pluralize(snakeCaseName)
+	util.PrintGreen("Add the following routes to RouteTable in config/app.go:\n")
+	for _, route := range []struct {
+		name       string
+		path       string
+		controller string
+	}{
+		{plural, "/" + plural, util.ToCamelCase(plural)},
+		{"new_" + snakeCaseName, "/" + plural + "/new", "New" + camelCaseName},
+		{snakeCaseName, "/" + plural + "/:id", camelCaseName},
+		{"edit_" + snakeCaseName, "/" + plural + "/:id/edit", "Edit" + camelCaseName},
+	} {
+		fmt.Printf(`
+	{
+		Name:       %q,
+		Path:       %q,
+		Controller: &controller.%s{},
+	},`, route.name, route.path, route.controller)
+	}
+	fmt.Println()
+}
+
+func skeletonDir(name string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	baseDir := filepath.Dir(filename)
+	return filepath.Join(baseDir, "skeleton", name)
+}
+
+func main() {
+	util.RunCommand(&generateScaffoldCommand{})
+}
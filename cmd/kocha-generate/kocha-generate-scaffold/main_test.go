@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_generateScaffoldCommand_Run(t *testing.T) {
+	// test for no arguments.
+	func() {
+		c := &generateScaffoldCommand{}
+		args := []string{}
+		err := c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = fmt.Errorf("no NAME given")
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+	}()
+
+	// test for unsupported ORM.
+	func() {
+		c := &generateScaffoldCommand{}
+		c.option.ORM = "invalid"
+		args := []string{"user"}
+		err := c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = fmt.Errorf("unsupported ORM: `invalid'")
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+	}()
+
+	// test for invalid argument formats.
+	func() {
+		c := &generateScaffoldCommand{}
+		for _, v := range []struct {
+			arg    string
+			expect interface{}
+		}{
+			{"field", fmt.Errorf("invalid argument format is specified: `field'")},
+			{":", fmt.Errorf("field name isn't specified: `:'")},
+			{"field:", fmt.Errorf("field type isn't specified: `field:'")},
+		} {
+			args := []string{"user", v.arg}
+			err := c.Run(args)
+			var actual interface{} = err
+			var expect interface{} = v.expect
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+			}
+		}
+	}()
+
+	func() {
+		tempdir, err := ioutil.TempDir("", "TestGenerateScaffold")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tempdir)
+		appDir := filepath.Join(tempdir, "src", "myapp")
+		if err := os.MkdirAll(appDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		os.Chdir(appDir)
+		origGOPATH := build.Default.GOPATH
+		build.Default.GOPATH = tempdir
+		defer func() {
+			build.Default.GOPATH = origGOPATH
+		}()
+		var modelArgs []string
+		origGenerateModel := generateModel
+		generateModel = func(args []string) error {
+			modelArgs = args
+			return nil
+		}
+		defer func() {
+			generateModel = origGenerateModel
+		}()
+		f, err := os.OpenFile(os.DevNull, os.O_WRONLY, os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		oldStdout, oldStderr := os.Stdout, os.Stderr
+		os.Stdout, os.Stderr = f, f
+		defer func() {
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+		}()
+		c := &generateScaffoldCommand{}
+		args := []string{"user", "name:string", "age:int"}
+		err = c.Run(args)
+		var actual interface{} = err
+		var expect interface{} = nil
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v) => %#v; want %#v`, args, actual, expect)
+		}
+		actual = modelArgs
+		expect = []string{"--orm", "genmai", "user", "name:string", "age:int"}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`generate(%#v); model generator args => %#v; want %#v`, args, actual, expect)
+		}
+		for _, v := range []string{
+			filepath.Join("app", "controller", "user.go"),
+			filepath.Join("app", "view", "users.html.tmpl"),
+			filepath.Join("app", "view", "user.html.tmpl"),
+			filepath.Join("app", "view", "new_user.html.tmpl"),
+			filepath.Join("app", "view", "edit_user.html.tmpl"),
+		} {
+			if _, err := os.Stat(v); os.IsNotExist(err) {
+				t.Errorf("generate(%#v); file %#v is not exists; want exists", args, v)
+			}
+		}
+		buf, err := ioutil.ReadFile(filepath.Join("app", "controller", "user.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{
+			`"myapp/app/model"`,
+			`c.Params.From("user").Bind(user, "name", "age")`,
+			"func (ctrl *Users) POST(c *kocha.Context) error {",
+			"func (ctrl *User) PUT(c *kocha.Context) error {",
+			"func (ctrl *User) DELETE(c *kocha.Context) error {",
+		} {
+			if !strings.Contains(string(buf), s) {
+				t.Errorf("generate(%#v); controller doesn't contain %#v", args, s)
+			}
+		}
+	}()
+}
+
+func Test_pluralize(t *testing.T) {
+	for _, v := range []struct {
+		s      string
+		expect string
+	}{
+		{"user", "users"},
+		{"category", "categories"},
+		{"day", "days"},
+		{"box", "boxes"},
+		{"status", "statuses"},
+		{"match", "matches"},
+		{"blog_post", "blog_posts"},
+	} {
+		actual := pluralize(v.s)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`pluralize(%#v) => %#v; want %#v`, v.s, actual, expect)
+		}
+	}
+}
@@ -35,6 +35,7 @@ Generators:
     controller
     migration
     model
+    scaffold
     unit
 
 Options:
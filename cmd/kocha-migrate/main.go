@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"text/template"
+
+	"github.com/naoina/kocha/util"
+)
+
+type migrateCommand struct {
+	option struct {
+		DB    string `long:"db"`
+		Limit *int   `short:"n" long:"limit"`
+		To    string `long:"to"`
+		Help  bool   `short:"h" long:"help"`
+	}
+}
+
+func (c *migrateCommand) Name() string {
+	return "kocha migrate"
+}
+
+func (c *migrateCommand) Usage() string {
+	return fmt.Sprintf(`Usage: %s [OPTIONS] ACTION [IMPORT_PATH]
+
+Run the migrations of your application.
+
+Actions:
+    up                apply the pending migrations
+    down              roll back the applied migrations
+    redo              roll back the latest migration and apply it again
+    status            display the status of the migrations
+
+Options:
+    --db=NAME         name of a database config [default: "default"]
+    -n, --limit=N     number of migrations to be run
+                      negative N runs all migrations, and 0 runs nothing
+                      [default: all migrations for up, 1 for down]
+    --to=VERSION      migrate up or down to VERSION
+                      VERSION "0" for down rolls back all migrations
+    -h, --help        display this help and exit
+
+`, c.Name())
+}
+
+func (c *migrateCommand) Option() interface{} {
+	return &c.option
+}
+
+// Run runs the migrations by way of the temporary program that imports the
+// db and db/migration packages of the application.
+func (c *migrateCommand) Run(args []string) (err error) {
+	if len(args) < 1 || args[0] == "" {
+		return fmt.Errorf("no ACTION given")
+	}
+	action := args[0]
+	switch action {
+	case "up", "down":
+		// do nothing.
+	case "redo", "status":
+		if c.option.To != "" {
+			return fmt.Errorf("--to cannot be used with `%v'", action)
+		}
+	default:
+		return fmt.Errorf("unknown ACTION: %v", action)
+	}
+	if c.option.To != "" && c.option.Limit != nil {
+		return fmt.Errorf("--to and --limit cannot be used together")
+	}
+	if c.option.DB == "" {
+		c.option.DB = "default"
+	}
+	var appDir string
+	if len(args) > 1 {
+		appDir = args[1]
+	} else {
+		appDir, err = util.FindAppDir()
+		if err != nil {
+			return err
+		}
+	}
+	dbPkg, err := getPackage(path.Join(appDir, "db"))
+	if err != nil {
+		return fmt.Errorf(`cannot import "%s": %v`, path.Join(appDir, "db"), err)
+	}
+	migrationPkg, err := getPackage(path.Join(appDir, "db", "migration"))
+	if err != nil {
+		return fmt.Errorf(`cannot import "%s": %v`, path.Join(appDir, "db", "migration"), err)
+	}
+	tmpDir, err := filepath.Abs("tmp")
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(tmpDir, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	mainFilePath := filepath.ToSlash(filepath.Join(tmpDir, "migrate.go"))
+	file, err := os.Create(mainFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer file.Close()
+	t := template.Must(template.ParseFiles(filepath.Join(skeletonDir("migrate"), "migrate.go"+util.TemplateSuffix)))
+	data := map[string]interface{}{
+		"dbImportPath":        dbPkg.ImportPath,
+		"migrationImportPath": migrationPkg.ImportPath,
+		"dbconf":              c.option.DB,
+		"action":              action,
+		"limit":               c.limit(action),
+		"version":             c.option.To,
+	}
+	if err := t.Execute(file, data); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	file.Close()
+	return execCmd("go", "run", mainFilePath)
+}
+
+// limit returns the number of migrations to be run.
+// It returns -1 if all migrations should be run.
+func (c *migrateCommand) limit(action string) int {
+	if c.option.Limit != nil {
+		if *c.option.Limit < 0 {
+			return -1
+		}
+		return *c.option.Limit
+	}
+	if action == "down" {
+		return 1
+	}
+	return -1
+}
+
+func getPackage(importPath string) (*build.Package, error) {
+	return build.Import(importPath, "", build.FindOnly)
+}
+
+func execCmd(cmd string, args ...string) error {
+	command := exec.Command(cmd, args...)
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	return command.Run()
+}
+
+func skeletonDir(name string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	baseDir := filepath.Dir(filename)
+	return filepath.Join(baseDir, "skeleton", name)
+}
+
+func main() {
+	util.RunCommand(&migrateCommand{})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_migrateCommand_Name(t *testing.T) {
+	c := &migrateCommand{}
+	var actual interface{} = c.Name()
+	var expect interface{} = "kocha migrate"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`%T.Name() => %#v; want %#v`, c, actual, expect)
+	}
+}
+
+func Test_migrateCommand_Run_withInvalidArguments(t *testing.T) {
+	for _, v := range []struct {
+		args   []string
+		to     string
+		limit  *int
+		expect error
+	}{
+		{[]string{}, "", nil, fmt.Errorf("no ACTION given")},
+		{[]string{""}, "", nil, fmt.Errorf("no ACTION given")},
+		{[]string{"unknown"}, "", nil, fmt.Errorf("unknown ACTION: unknown")},
+		{[]string{"redo"}, "20140101000000", nil, fmt.Errorf("--to cannot be used with `redo'")},
+		{[]string{"status"}, "20140101000000", nil, fmt.Errorf("--to cannot be used with `status'")},
+		{[]string{"up"}, "20140101000000", intPtr(1), fmt.Errorf("--to and --limit cannot be used together")},
+	} {
+		c := &migrateCommand{}
+		c.option.To = v.to
+		c.option.Limit = v.limit
+		err := c.Run(v.args)
+		var actual interface{} = err
+		var expect interface{} = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%T.Run(%#v) => %#v; want %#v`, c, v.args, actual, expect)
+		}
+	}
+}
+
+func Test_migrateCommand_Run_withNoDBPackage(t *testing.T) {
+	c := &migrateCommand{}
+	args := []string{"up", "github.com/naoina/kocha/cmd/kocha-migrate/unknown"}
+	err := c.Run(args)
+	if err == nil {
+		t.Fatalf(`%T.Run(%#v) => nil; want error`, c, args)
+	}
+	actual := err.Error()
+	expect := `cannot import "github.com/naoina/kocha/cmd/kocha-migrate/unknown/db": `
+	if !strings.HasPrefix(actual, expect) {
+		t.Errorf(`%T.Run(%#v) => %#v; want %#v`, c, args, actual, expect)
+	}
+}
+
+func Test_migrateCommand_limit(t *testing.T) {
+	for _, v := range []struct {
+		action string
+		limit  *int
+		expect int
+	}{
+		{"up", nil, -1},
+		{"down", nil, 1},
+		{"up", intPtr(0), 0},
+		{"down", intPtr(0), 0},
+		{"up", intPtr(2), 2},
+		{"down", intPtr(3), 3},
+		{"down", intPtr(-1), -1},
+		{"down", intPtr(-5), -1},
+	} {
+		c := &migrateCommand{}
+		c.option.Limit = v.limit
+		actual := c.limit(v.action)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%T.limit(%#v) with Limit %v => %#v; want %#v`, c, v.action, v.limit, actual, expect)
+		}
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/naoina/kocha"
+	"github.com/naoina/kocha/util"
+)
+
+const (
+	defaultFormat = "table"
+)
+
+type routesCommand struct {
+	option struct {
+		Format string `short:"f" long:"format"`
+		Help   bool   `short:"h" long:"help"`
+	}
+}
+
+func (c *routesCommand) Name() string {
+	return "kocha routes"
+}
+
+func (c *routesCommand) Usage() string {
+	return fmt.Sprintf(`Usage: %s [OPTIONS] [IMPORT_PATH]
+
+Display the routing table of your application.
+
+Options:
+    -f, --format=FORMAT  output format [default: "%s"]
+                         available formats: table, json
+    -h, --help           display this help and exit
+
+`, c.Name(), defaultFormat)
+}
+
+func (c *routesCommand) Option() interface{} {
+	return &c.option
+}
+
+// routesResult is the output of the temporary program.
+type routesResult struct {
+	Routes   []kocha.RouteInfo `json:"routes"`
+	Warnings []string          `json:"warnings"`
+}
+
+// Run displays the routing table by way of the temporary program that imports
+// the config package of the application.
+func (c *routesCommand) Run(args []string) (err error) {
+	if c.option.Format == "" {
+		c.option.Format = defaultFormat
+	}
+	switch c.option.Format {
+	case "table", "json":
+		// do nothing.
+	default:
+		return fmt.Errorf("unknown FORMAT: %v", c.option.Format)
+	}
+	var appDir string
+	if len(args) > 0 {
+		appDir = args[0]
+	} else {
+		appDir, err = util.FindAppDir()
+		if err != nil {
+			return err
+		}
+	}
+	configPkg, err := getPackage(path.Join(appDir, "config"))
+	if err != nil {
+		return fmt.Errorf(`cannot import "%s": %v`, path.Join(appDir, "config"), err)
+	}
+	tmpDir, err := filepath.Abs("tmp")
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(tmpDir, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	mainFilePath := filepath.ToSlash(filepath.Join(tmpDir, "routes.go"))
+	file, err := os.Create(mainFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer file.Close()
+	t := template.Must(template.ParseFiles(filepath.Join(skeletonDir("routes"), "routes.go"+util.TemplateSuffix)))
+	data := map[string]interface{}{
+		"configImportPath": configPkg.ImportPath,
+	}
+	if err := t.Execute(file, data); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	file.Close()
+	output, err := execCmd("go", "run", mainFilePath)
+	if err != nil {
+		return err
+	}
+	var result routesResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return fmt.Errorf("failed to read the routing table: %v", err)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "%s: WARNING: %s\n", c.Name(), warning)
+	}
+	return printRoutes(os.Stdout, c.option.Format, result.Routes)
+}
+
+// printRoutes prints the routes to w in format.
+func printRoutes(w io.Writer, format string, routes []kocha.RouteInfo) error {
+	switch format {
+	case "json":
+		if routes == nil {
+			routes = []kocha.RouteInfo{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tMETHODS\tHOST\tPATH\tPARAMS\tHANDLER")
+		for _, route := range routes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				route.Name,
+				strings.Join(route.Methods, ","),
+				orDash(route.Host),
+				route.Path,
+				orDash(strings.Join(route.ParamNames, ",")),
+				route.Handler)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown FORMAT: %v", format)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func getPackage(importPath string) (*build.Package, error) {
+	return build.Import(importPath, "", build.FindOnly)
+}
+
+// execCmd runs cmd and returns its standard output.
+func execCmd(cmd string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	command := exec.Command(cmd, args...)
+	command.Stdout = &stdout
+	command.Stderr = os.Stderr
+	if err := command.Run(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func skeletonDir(name string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	baseDir := filepath.Dir(filename)
+	return filepath.Join(baseDir, "skeleton", name)
+}
+
+func main() {
+	util.RunCommand(&routesCommand{})
+}
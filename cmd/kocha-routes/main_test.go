@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/naoina/kocha"
+)
+
+func Test_routesCommand_Name(t *testing.T) {
+	c := &routesCommand{}
+	var actual interface{} = c.Name()
+	var expect interface{} = "kocha routes"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`%T.Name() => %#v; want %#v`, c, actual, expect)
+	}
+}
+
+func Test_routesCommand_Run_withUnknownFormat(t *testing.T) {
+	c := &routesCommand{}
+	c.option.Format = "xml"
+	args := []string{}
+	err := c.Run(args)
+	var actual interface{} = err
+	var expect interface{} = fmt.Errorf("unknown FORMAT: xml")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`%T.Run(%#v) => %#v; want %#v`, c, args, actual, expect)
+	}
+}
+
+func Test_routesCommand_Run_withNoConfigPackage(t *testing.T) {
+	c := &routesCommand{}
+	args := []string{"github.com/naoina/kocha/cmd/kocha-routes/unknown"}
+	err := c.Run(args)
+	if err == nil {
+		t.Fatalf(`%T.Run(%#v) => nil; want error`, c, args)
+	}
+	actual := err.Error()
+	expect := `cannot import "github.com/naoina/kocha/cmd/kocha-routes/unknown/config": `
+	if !strings.HasPrefix(actual, expect) {
+		t.Errorf(`%T.Run(%#v) => %#v; want %#v`, c, args, actual, expect)
+	}
+}
+
+func Test_printRoutes(t *testing.T) {
+	routes := []kocha.RouteInfo{
+		{Name: "root", Methods: []string{"GET"}, Path: "/", ParamNames: []string{}, Handler: "*controller.Root"},
+		{Name: "tenant_user", Methods: []string{"GET", "DELETE"}, Host: ":tenant.example.com", Path: "/users/:id<int>", ParamNames: []string{":tenant", ":id"}, Handler: "*controller.User"},
+	}
+	for _, v := range []struct {
+		format string
+		expect string
+	}{
+		{"table", `NAME         METHODS     HOST                 PATH             PARAMS       HANDLER
+root         GET         -                    /                -            *controller.Root
+tenant_user  GET,DELETE  :tenant.example.com  /users/:id<int>  :tenant,:id  *controller.User
+`},
+		{"json", `[
+  {
+    "name": "root",
+    "methods": [
+      "GET"
+    ],
+    "path": "/",
+    "param_names": [],
+    "handler": "*controller.Root"
+  },
+  {
+    "name": "tenant_user",
+    "methods": [
+      "GET",
+      "DELETE"
+    ],
+    "host": ":tenant.example.com",
+    "path": "/users/:id<int>",
+    "param_names": [
+      ":tenant",
+      ":id"
+    ],
+    "handler": "*controller.User"
+  }
+]
+`},
+	} {
+		var buf bytes.Buffer
+		if err := printRoutes(&buf, v.format, routes); err != nil {
+			t.Errorf(`printRoutes(w, %#v, %#v) => %#v; want nil`, v.format, routes, err)
+			continue
+		}
+		actual := buf.String()
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`printRoutes(w, %#v, %#v) => %q; want %q`, v.format, routes, actual, expect)
+		}
+	}
+}
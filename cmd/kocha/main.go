@@ -41,6 +41,7 @@ Commands:
     build             build your application (alias: "b")
     run               run the your application
     migrate           run the migrations
+    routes            display the routing table
 
 Options:
     -h, --help        display this help and exit
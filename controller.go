@@ -64,11 +64,31 @@ type Patcher interface {
 	PATCH(c *Context) error
 }
 
+// MethodAllower is the interface that the controller that reports the HTTP
+// methods that it handles.
+// If the controller of the route implements MethodAllower, the route handles
+// only the methods that AllowedMethods returns, and they are used as the
+// Allow header.
+type MethodAllower interface {
+	AllowedMethods() []string
+}
+
 type requestHandler func(c *Context) error
 
+// anyMethodController is the interface that the controller that handles the
+// requests of all methods, including the methods that Controller doesn't
+// have, by serve.
+type anyMethodController interface {
+	serve(c *Context) error
+}
+
 // DefaultController implements Controller interface.
 // This can be used to save the trouble to implement all of the methods of
 // Controller interface.
+//
+// The methods that are promoted from DefaultController aren't regarded as
+// the methods that are handled by the route, so they aren't listed in the
+// Allow header.
 type DefaultController struct {
 }
 
@@ -142,10 +162,23 @@ type Context struct {
 
 	// Errors represents the map of errors that related to the form values.
 	// A map key is field name, and value is slice of errors.
-	// Errors will be set by Context.Params.Bind().
+	// Errors will be set by Context.Params.Bind(), including the failures of
+	// the `validate` struct tag rules.
 	Errors map[string][]*ParamError
+
+	// sessionAction is the action to the session that will be processed by
+	// SessionMiddleware.
+	sessionAction sessionAction
 }
 
+type sessionAction int
+
+const (
+	sessionSave sessionAction = iota
+	sessionRegenerate
+	sessionDestroy
+)
+
 func newContext() *Context {
 	c := contextPool.Get().(*Context)
 	c.reset()
@@ -350,6 +383,62 @@ func (c *Context) Redirect(url string, permanently bool) error {
 	return nil
 }
 
+// RegenerateSession makes SessionMiddleware issue a new key of the session
+// while keeping the session data, and delete the old one from the store if
+// the store implements SessionDeleter.
+// It should be called after the login to prevent the session fixation.
+func (c *Context) RegenerateSession() {
+	if c.sessionAction != sessionDestroy {
+		c.sessionAction = sessionRegenerate
+	}
+}
+
+// DestroySession clears the session data, and makes SessionMiddleware expire
+// the session cookie and delete the session from the store if the store
+// implements SessionDeleter.
+// The session data that is set after DestroySession in the same request
+// will not be saved.
+func (c *Context) DestroySession() {
+	c.Session.Clear()
+	c.sessionAction = sessionDestroy
+}
+
+// Bind binds the values of fieldNames in the request to obj according to
+// the Content-Type of the request.
+//
+// The request body is decoded as JSON if Content-Type is "application/json"
+// or has "+json" suffix, as XML if "application/xml", "text/xml" or has
+// "+xml" suffix, and as MessagePack if "application/msgpack" or
+// "application/x-msgpack". The decoded values are bound in the same way as
+// Params.Bind by the names of form values. e.g. the name of "Tokyo" of
+// `{"address": {"city": "Tokyo"}}` is "address.city", and of
+// `<user><address><city>Tokyo</city></address></user>` is also
+// "address.city" because the root element of XML is omitted.
+// The numbers in the request body are parsed by strconv regardless of the
+// number format.
+// For other Content-Types, Bind is the same as c.Params.Bind.
+//
+// If the request body cannot be decoded, Bind returns ErrInvalidBody.
+func (c *Context) Bind(obj interface{}, fieldNames ...string) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	decode := bodyDecoderFor(mediaType)
+	if decode == nil {
+		if c.Params == nil {
+			c.Params = c.newParams()
+		}
+		return c.Params.Bind(obj, fieldNames...)
+	}
+	values := url.Values{}
+	if err := decode(c.Request.Body, values); err != nil {
+		c.App.Logger.Warnf("kocha: Bind: %v", err)
+		return ErrInvalidBody
+	}
+	params := newParams(c, values, "")
+	params.canonical = true
+	defer params.reuse()
+	return params.Bind(obj, fieldNames...)
+}
+
 // Invoke is shorthand of c.App.Invoke.
 func (c *Context) Invoke(unit Unit, newFunc func(), defaultFunc func()) {
 	c.App.Invoke(unit, newFunc, defaultFunc)
@@ -437,6 +526,7 @@ func (c *Context) reset() {
 	c.Params = nil
 	c.Session = nil
 	c.Flash = nil
+	c.sessionAction = sessionSave
 }
 
 func (c *Context) reuse() {
@@ -458,6 +548,51 @@ func (ss *StaticServe) GET(c *Context) error {
 	return c.SendFile(path.Path)
 }
 
+// HTTPHandler is generic controller that passes the requests of all methods
+// to an http.Handler. It includes OPTIONS and the methods that Controller
+// doesn't have, such as PROPFIND, so that the router doesn't respond to them
+// by itself.
+//
+// If Param is specified, it must be the name of the wildcard parameter of the
+// route, and the path before the wildcard parameter is stripped from the
+// request path that Handler receives. For example, a request to
+// "/metrics/foo" is passed to Handler as "/foo" if the route path is
+// "/metrics/*path" and Param is "path".
+// Handler runs inside the middleware chain of the application.
+type HTTPHandler struct {
+	Handler http.Handler
+	Param   string
+}
+
+func (h *HTTPHandler) GET(c *Context) error    { return h.serve(c) }
+func (h *HTTPHandler) POST(c *Context) error   { return h.serve(c) }
+func (h *HTTPHandler) PUT(c *Context) error    { return h.serve(c) }
+func (h *HTTPHandler) DELETE(c *Context) error { return h.serve(c) }
+func (h *HTTPHandler) HEAD(c *Context) error   { return h.serve(c) }
+func (h *HTTPHandler) PATCH(c *Context) error  { return h.serve(c) }
+
+func (h *HTTPHandler) serve(c *Context) error {
+	if h.Param == "" {
+		h.Handler.ServeHTTP(c.Response, c.Request.Request)
+		return nil
+	}
+	path := "/" + c.Params.Get(h.Param)
+	c.Params.Del(h.Param)
+	serveHTTPWithPath(h.Handler, c, path)
+	return nil
+}
+
+// serveHTTPWithPath calls h.ServeHTTP with the copy of the request of c that
+// the URL path is replaced with path.
+func serveHTTPWithPath(h http.Handler, c *Context, path string) {
+	req := new(http.Request)
+	*req = *c.Request.Request
+	u := *req.URL
+	u.Path, u.RawPath = path, ""
+	req.URL = &u
+	h.ServeHTTP(c.Response, req)
+}
+
 var internalServerErrorController = &ErrorController{
 	StatusCode: http.StatusInternalServerError,
 }
@@ -16,6 +16,7 @@ import (
 
 	"github.com/naoina/kocha"
 	"github.com/naoina/kocha/log"
+	"github.com/ugorji/go/codec"
 )
 
 func TestMimeTypeFormats(t *testing.T) {
@@ -683,3 +684,166 @@ func TestContext_Redirect(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPHandler(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%v %v %v", r.Method, r.URL.Path, r.URL.RawQuery)
+	})
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&testHeaderMiddleware{"app"}, &kocha.DispatchMiddleware{}}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "metrics", Path: "/metrics", Controller: &kocha.HTTPHandler{Handler: handler}},
+		{Name: "files", Path: "/files/*path", Controller: &kocha.HTTPHandler{Handler: handler, Param: "path"}},
+		{Name: "debug", Path: "/debug/*path", Controller: &kocha.HTTPHandler{Handler: handler}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		method string
+		uri    string
+		expect string
+	}{
+		{"GET", "/metrics", "GET /metrics "},
+		{"POST", "/metrics?q=1", "POST /metrics q=1"},
+		{"DELETE", "/files/path/to/file", "DELETE /path/to/file "},
+		{"PATCH", "/files/", "PATCH / "},
+		{"GET", "/debug/pprof/heap?debug=1", "GET /debug/pprof/heap debug=1"},
+		{"OPTIONS", "/metrics", "OPTIONS /metrics "},
+		{"OPTIONS", "/files/path/to/file", "OPTIONS /path/to/file "},
+		{"PROPFIND", "/files/dir", "PROPFIND /dir "},
+	} {
+		req, err := http.NewRequest(v.method, v.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var actual interface{} = w.Body.String()
+		var expect interface{} = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+		actual = w.Header().Get("X-Test-Middleware")
+		expect = "app"
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v; X-Test-Middleware => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+	}
+}
+
+func TestHTTPHandler_withInvalidParam(t *testing.T) {
+	config := newConfig()
+	config.RouteTable = kocha.RouteTable{
+		{Name: "files", Path: "/files/*filepath", Controller: &kocha.HTTPHandler{Handler: http.NotFoundHandler(), Param: "path"}},
+	}
+	_, err := kocha.New(config)
+	var actual interface{} = fmt.Sprint(err)
+	var expect interface{} = `kocha: route files: HTTPHandler.Param "path" is not a wildcard parameter of /files/*filepath`
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`kocha.New(config) => (_, %#v); want (_, %#v)`, actual, expect)
+	}
+}
+
+func TestContext_Bind(t *testing.T) {
+	type Item struct {
+		Name  string
+		Count int
+	}
+	type User struct {
+		Name  string `validate:"required"`
+		Age   int
+		Tags  []string
+		Items []Item
+		Meta  map[string]string
+	}
+	var msgpackBody bytes.Buffer
+	if err := codec.NewEncoder(&msgpackBody, &codec.MsgpackHandle{}).Encode(map[string]interface{}{
+		"name":  "naoina",
+		"age":   17,
+		"tags":  []string{"a", "b"},
+		"items": []map[string]interface{}{{"name": "pen", "count": 2}},
+		"meta":  map[string]string{"color": "red"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectUser := &User{
+		Name:  "naoina",
+		Age:   17,
+		Tags:  []string{"a", "b"},
+		Items: []Item{{Name: "pen", Count: 2}},
+		Meta:  map[string]string{"color": "red"},
+	}
+	for _, v := range []struct {
+		contentType string
+		body        string
+		expect      *User
+		expectErrs  map[string][]string
+		expectErr   error
+	}{
+		{"application/json; charset=utf-8", `{"name": "naoina", "age": 17, "tags": ["a", "b"], "items": [{"name": "pen", "count": 2}], "meta": {"color": "red"}, "admin": true}`,
+			expectUser, map[string][]string{}, nil},
+		{"application/vnd.api+json", `{"age": "x", "items": {"name": "pen"}}`,
+			&User{Items: []Item{{Name: "pen"}}}, map[string][]string{
+				"name": {"name is required"},
+				"age":  {"age is invalid format"},
+			}, nil},
+		{"application/json", `{"name": `, &User{}, map[string][]string{}, kocha.ErrInvalidBody},
+		{"application/json", ``, &User{}, map[string][]string{"name": {"name is required"}}, nil},
+		{"application/xml", `<user><name>naoina</name><age>17</age><tags>a</tags><tags>b</tags><items><name>pen</name><count>2</count></items><meta color="red"/></user>`,
+			expectUser, map[string][]string{}, nil},
+		{"text/xml", `<user><name>naoina</name><items><name>pen</name></items><items><name>note</name></items></user>`,
+			&User{Name: "naoina", Items: []Item{{Name: "pen"}, {Name: "note"}}}, map[string][]string{}, nil},
+		{"application/xml", `<user><name>naoina`, &User{}, map[string][]string{}, kocha.ErrInvalidBody},
+		{"application/x-msgpack", msgpackBody.String(), expectUser, map[string][]string{}, nil},
+		{"application/x-www-form-urlencoded", `name=naoina&age=17&tags=a&tags=b&items[0].name=pen&items[0].count=2&meta[color]=red`,
+			expectUser, map[string][]string{}, nil},
+	} {
+		user := &User{}
+		var errs map[string][]string
+		var bindErr error
+		config := newConfig()
+		config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+		config.Middlewares = []kocha.Middleware{&kocha.FormMiddleware{}, &kocha.DispatchMiddleware{}}
+		config.RouteTable = kocha.RouteTable{
+			{Name: "root", Path: "/", Method: "POST", Handler: func(c *kocha.Context) error {
+				bindErr = c.Bind(user, "name", "age", "tags", "items", "meta")
+				errs = map[string][]string{}
+				for name, es := range c.Errors {
+					for _, e := range es {
+						errs[name] = append(errs[name], e.Error())
+					}
+				}
+				return c.RenderText("")
+			}},
+		}
+		app, err := kocha.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/", strings.NewReader(v.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", v.contentType)
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		var actual interface{} = bindErr
+		var expect interface{} = v.expectErr
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Bind with %v %#v => %#v; want %#v`, v.contentType, v.body, actual, expect)
+		}
+		actual = user
+		expect = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Bind with %v %#v; obj => %#v; want %#v`, v.contentType, v.body, actual, expect)
+		}
+		actual = errs
+		expect = v.expectErrs
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Bind with %v %#v; Errors => %#v; want %#v`, v.contentType, v.body, actual, expect)
+		}
+	}
+}
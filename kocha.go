@@ -105,6 +105,14 @@ func New(config *Config) (*Application, error) {
 	if app.Config.MaxClientBodySize < 1 {
 		config.MaxClientBodySize = DefaultMaxClientBodySize
 	}
+	if len(app.Config.TimeFormats) < 1 {
+		config.TimeFormats = DefaultTimeFormats
+	}
+	if app.Config.NumberFormat != "" {
+		if _, err := parseNumberFormat(app.Config.NumberFormat); err != nil {
+			return nil, fmt.Errorf("kocha: NumberFormat: %v", err)
+		}
+	}
 	if err := app.validateMiddlewares(); err != nil {
 		return nil, err
 	}
@@ -210,7 +218,34 @@ func (app *Application) buildEvent() (err error) {
 }
 
 func (app *Application) validateMiddlewares() error {
-	for _, m := range app.Config.Middlewares {
+	validated := make(map[Middleware]bool)
+	if err := validateMiddlewares(app.Config.Middlewares, validated); err != nil {
+		return err
+	}
+	for _, route := range app.Config.RouteTable {
+		if err := validateMiddlewares(route.Middlewares, validated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *Application) wrapMiddlewares(c *Context) func() error {
+	return chainMiddlewares(app, c, app.Config.Middlewares, nullMiddlewareNext)
+}
+
+// validateMiddlewares validates middlewares that aren't in validated yet, and
+// adds them to validated.
+// A middleware can be shared by the routes of RouteGroup, so each instance
+// must be validated only once.
+func validateMiddlewares(middlewares []Middleware, validated map[Middleware]bool) error {
+	for _, m := range middlewares {
+		if m != nil && reflect.TypeOf(m).Comparable() {
+			if validated[m] {
+				continue
+			}
+			validated[m] = true
+		}
 		if v, ok := m.(Validator); ok {
 			if err := v.Validate(); err != nil {
 				return err
@@ -220,10 +255,12 @@ func (app *Application) validateMiddlewares() error {
 	return nil
 }
 
-func (app *Application) wrapMiddlewares(c *Context) func() error {
-	wrapped := nullMiddlewareNext
-	for i := len(app.Config.Middlewares) - 1; i >= 0; i-- {
-		f, next := app.Config.Middlewares[i].Process, wrapped
+// chainMiddlewares returns a function that processes middlewares in order,
+// and then calls last.
+func chainMiddlewares(app *Application, c *Context, middlewares []Middleware, last func() error) func() error {
+	wrapped := last
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		f, next := middlewares[i].Process, wrapped
 		wrapped = func() error {
 			return f(app, c, next)
 		}
@@ -249,6 +286,8 @@ type Config struct {
 	Logger            *LoggerConfig // logger config.
 	Event             *Event        // event config.
 	MaxClientBodySize int64         // maximum size of request body, DefaultMaxClientBodySize if 0
+	TimeFormats       []string      // layouts of time form values, DefaultTimeFormats if empty.
+	NumberFormat      string        // format of number form values such as "1,234.5", parsed by strconv if empty.
 
 	ResourceSet ResourceSet
 }
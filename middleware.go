@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/naoina/kocha/log"
@@ -79,11 +80,37 @@ type SessionMiddleware struct {
 
 	// Expiration of session data, in seconds, from now. (not cookie expiration)
 	// 0 is for persistent.
+	// It must be positive with SessionKVSStore unless SessionKVSStore.Expires
+	// is specified, because the session data is stored in the server.
 	SessionExpires time.Duration
 	HttpOnly       bool
 	ExpiresKey     string
+
+	// Domain attribute of session cookie.
+	// If empty, session cookie is sent to only the host that set it.
+	Domain string
+
+	// Path attribute of session cookie.
+	// If empty, "/" is used.
+	Path string
+
+	// SameSite attribute of session cookie.
+	// If 0, SameSite attribute is not set.
+	SameSite http.SameSite
+
+	// If true, Secure attribute of session cookie is always set.
+	// Otherwise, it is set only if the request is over SSL.
+	Secure bool
+
+	// If true, "__Host-" prefix is added to the name of session cookie.
+	// Secure attribute is always set, and Path attribute must be "/" and
+	// Domain attribute must not be set by the prefix.
+	HostPrefix bool
 }
 
+// sessionCookieHostPrefix is the cookie name prefix for SessionMiddleware.HostPrefix.
+const sessionCookieHostPrefix = "__Host-"
+
 func (m *SessionMiddleware) Process(app *Application, c *Context, next func() error) error {
 	if err := m.before(app, c); err != nil {
 		return err
@@ -105,9 +132,23 @@ func (m *SessionMiddleware) Validate() error {
 	if m.Name == "" {
 		return fmt.Errorf("kocha: session: Name must be specified")
 	}
+	if m.HostPrefix {
+		if m.Domain != "" {
+			return fmt.Errorf("kocha: session: Domain cannot be specified with HostPrefix")
+		}
+		if m.Path != "" && m.Path != "/" {
+			return fmt.Errorf(`kocha: session: Path must be "/" with HostPrefix, but %q`, m.Path)
+		}
+	}
 	if m.ExpiresKey == "" {
 		m.ExpiresKey = "_kocha._sess._expires"
 	}
+	if store, ok := m.Store.(*SessionKVSStore); ok && store.Expires == 0 && m.SessionExpires > 0 {
+		store.Expires = m.SessionExpires
+	}
+	if store, ok := m.Store.(*SessionCookieStore); ok && store.CookieName == "" {
+		store.CookieName = m.cookieName()
+	}
 	if v, ok := m.Store.(Validator); ok {
 		return v.Validate()
 	}
@@ -129,7 +170,7 @@ func (m *SessionMiddleware) before(app *Application, c *Context) (err error) {
 		}
 		err = nil
 	}()
-	cookie, err := c.Request.Cookie(m.Name)
+	cookie, err := c.Request.Cookie(m.cookieName())
 	if err != nil {
 		return NewErrSession("new session")
 	}
@@ -153,6 +194,26 @@ func (m *SessionMiddleware) before(app *Application, c *Context) (err error) {
 }
 
 func (m *SessionMiddleware) after(app *Application, c *Context) (err error) {
+	switch c.sessionAction {
+	case sessionRegenerate:
+		if err := m.deleteSession(c); err != nil {
+			return err
+		}
+		delete(c.Session, sessionIDKey)
+	case sessionDestroy:
+		if err := m.deleteSession(c); err != nil {
+			return err
+		}
+		cookie := m.newSessionCookie(app, c)
+		cookie.Expires = time.Unix(1, 0)
+		cookie.MaxAge = -1
+		c.Response.SetCookie(cookie)
+		return nil
+	}
+	if len(c.Session) == 0 && !m.hasSessionCookie(c) {
+		// a new session that has no data doesn't need to be saved.
+		return nil
+	}
 	expires, _ := m.expiresFromDuration(m.SessionExpires)
 	c.Session[m.ExpiresKey] = strconv.FormatInt(expires.Unix(), 10)
 	cookie := m.newSessionCookie(app, c)
@@ -164,19 +225,53 @@ func (m *SessionMiddleware) after(app *Application, c *Context) (err error) {
 	return nil
 }
 
+// hasSessionCookie reports whether the request has the session cookie.
+func (m *SessionMiddleware) hasSessionCookie(c *Context) bool {
+	_, err := c.Request.Cookie(m.cookieName())
+	return err == nil
+}
+
+// deleteSession deletes the session of the request cookie from the store if
+// the store implements SessionDeleter.
+func (m *SessionMiddleware) deleteSession(c *Context) error {
+	deleter, ok := m.Store.(SessionDeleter)
+	if !ok {
+		return nil
+	}
+	cookie, err := c.Request.Cookie(m.cookieName())
+	if err != nil {
+		return nil
+	}
+	return deleter.Delete(cookie.Value)
+}
+
 func (m *SessionMiddleware) newSessionCookie(app *Application, c *Context) *http.Cookie {
 	expires, maxAge := m.expiresFromDuration(m.CookieExpires)
+	path := m.Path
+	if path == "" {
+		path = "/"
+	}
 	return &http.Cookie{
-		Name:     m.Name,
+		Name:     m.cookieName(),
 		Value:    "",
-		Path:     "/",
+		Path:     path,
+		Domain:   m.Domain,
 		Expires:  expires,
 		MaxAge:   maxAge,
-		Secure:   c.Request.IsSSL(),
+		Secure:   m.Secure || m.HostPrefix || c.Request.IsSSL(),
 		HttpOnly: m.HttpOnly,
+		SameSite: m.SameSite,
 	}
 }
 
+// cookieName returns the name of session cookie.
+func (m *SessionMiddleware) cookieName() string {
+	if m.HostPrefix {
+		return sessionCookieHostPrefix + m.Name
+	}
+	return m.Name
+}
+
 func (m *SessionMiddleware) expiresFromDuration(d time.Duration) (expires time.Time, maxAge int) {
 	switch d {
 	case -1:
@@ -262,18 +357,54 @@ type DispatchMiddleware struct{}
 
 // Process implements the Middleware interface.
 func (m *DispatchMiddleware) Process(app *Application, c *Context, next func() error) error {
-	name, handler, params, found := app.Router.dispatch(c.Request)
+	route, handler, params, allowed, found := app.Router.dispatch(c.Request)
 	if !found {
-		handler = (&ErrorController{
-			StatusCode: http.StatusNotFound,
-		}).GET
+		switch {
+		case allowed == nil:
+			handler = (&ErrorController{
+				StatusCode: http.StatusNotFound,
+			}).GET
+		case strings.ToUpper(c.Request.Method) == "OPTIONS":
+			handler = optionsHandler(allowed)
+		default:
+			handler = methodNotAllowedHandler(allowed)
+		}
+	}
+	if route != nil {
+		c.Name = route.Name
 	}
-	c.Name = name
 	if c.Params == nil {
 		c.Params = c.newParams()
 	}
 	for _, param := range params {
 		c.Params.Add(param.Name, param.Value)
 	}
-	return handler(c)
+	if !found {
+		return handler(c)
+	}
+	return chainMiddlewares(app, c, route.Middlewares, func() error {
+		return handler(c)
+	})()
+}
+
+// methodNotAllowedHandler returns a handler that responds with the HTTP 405
+// Method Not Allowed and the Allow header of allowed.
+func methodNotAllowedHandler(allowed []string) requestHandler {
+	return func(c *Context) error {
+		c.Response.Header().Set("Allow", strings.Join(allowed, ", "))
+		return (&ErrorController{
+			StatusCode: http.StatusMethodNotAllowed,
+		}).GET(c)
+	}
+}
+
+// optionsHandler returns a handler that responds to OPTIONS request with the
+// Allow header of allowed.
+func optionsHandler(allowed []string) requestHandler {
+	return func(c *Context) error {
+		c.Response.Header().Set("Allow", strings.Join(allowed, ", "))
+		c.Response.StatusCode = http.StatusOK
+		c.Response.WriteHeader(http.StatusOK)
+		return nil
+	}
 }
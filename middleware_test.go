@@ -421,6 +421,7 @@ func TestSessionMiddleware_After(t *testing.T) {
 	m.SessionExpires = time.Duration(1) * time.Second
 	m.CookieExpires = time.Duration(2) * time.Second
 	if err := m.Process(app, c, func() error {
+		c.Session.Set("brown fox", "lazy dog")
 		return nil
 	}); err != nil {
 		t.Error(err)
@@ -429,6 +430,7 @@ func TestSessionMiddleware_After(t *testing.T) {
 		actual   interface{} = c.Session
 		expected interface{} = kocha.Session{
 			m.ExpiresKey: "1383820444", // + time.Duration(1) * time.Second
+			"brown fox":  "lazy dog",
 		}
 	)
 	if !reflect.DeepEqual(actual, expected) {
@@ -493,6 +495,73 @@ func TestSessionMiddleware_After(t *testing.T) {
 	}
 }
 
+func TestSessionMiddleware_After_withCookieAttributes(t *testing.T) {
+	app := kocha.NewTestApp()
+	for _, v := range []struct {
+		m      *kocha.SessionMiddleware
+		expect *http.Cookie
+	}{
+		{&kocha.SessionMiddleware{Name: "test_session"}, &http.Cookie{Name: "test_session", Path: "/"}},
+		{&kocha.SessionMiddleware{
+			Name:     "test_session",
+			Domain:   "example.com",
+			Path:     "/app",
+			SameSite: http.SameSiteLaxMode,
+			Secure:   true,
+		}, &http.Cookie{Name: "test_session", Domain: "example.com", Path: "/app", SameSite: http.SameSiteLaxMode, Secure: true}},
+		{&kocha.SessionMiddleware{
+			Name:       "test_session",
+			SameSite:   http.SameSiteStrictMode,
+			HostPrefix: true,
+		}, &http.Cookie{Name: "__Host-test_session", Path: "/", SameSite: http.SameSiteStrictMode, Secure: true}},
+	} {
+		r, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		req, res := &kocha.Request{Request: r}, &kocha.Response{ResponseWriter: w}
+		c := &kocha.Context{Request: req, Response: res}
+		v.m.Store = &NullSessionStore{}
+		if err := v.m.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := v.m.Process(app, c, func() error {
+			c.Session.Set("brown fox", "lazy dog")
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		cookie := res.Cookies()[0]
+		actual := []interface{}{cookie.Name, cookie.Domain, cookie.Path, cookie.SameSite, cookie.Secure}
+		expect := []interface{}{v.expect.Name, v.expect.Domain, v.expect.Path, v.expect.SameSite, v.expect.Secure}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SessionMiddleware.Process() with %#v; cookie => %#v; want %#v`, v.m, actual, expect)
+		}
+	}
+
+	// session cookie with "__Host-" prefix is loaded.
+	m := &kocha.SessionMiddleware{Name: "test_session", Store: kocha.NewTestSessionCookieStore(), HostPrefix: true, ExpiresKey: "_kocha._sess._expires"}
+	value, err := m.Store.Save(kocha.Session{"name": "naoina", m.ExpiresKey: "9999999999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCookie(&http.Cookie{Name: "__Host-test_session", Value: value})
+	c := &kocha.Context{Request: &kocha.Request{Request: r}, Response: &kocha.Response{ResponseWriter: httptest.NewRecorder()}}
+	if err := m.Process(app, c, func() error {
+		if actual, expect := c.Session.Get("name"), "naoina"; actual != expect {
+			t.Errorf(`SessionMiddleware.Process() with HostPrefix; Session.Get("name") => %#v; want %#v`, actual, expect)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 type ValidateTestSessionStore struct{ validated bool }
 
 func (s *ValidateTestSessionStore) Save(sess kocha.Session) (string, error) { return "", nil }
@@ -531,6 +600,18 @@ func TestSessionMiddleware_Validate(t *testing.T) {
 			Name:  "test_session",
 			Store: &ValidateTestSessionStore{},
 		}, fmt.Errorf("session store validate error")},
+		{&kocha.SessionMiddleware{
+			Name:       "test_session",
+			Store:      &NullSessionStore{},
+			Domain:     "example.com",
+			HostPrefix: true,
+		}, fmt.Errorf("kocha: session: Domain cannot be specified with HostPrefix")},
+		{&kocha.SessionMiddleware{
+			Name:       "test_session",
+			Store:      &NullSessionStore{},
+			Path:       "/app",
+			HostPrefix: true,
+		}, fmt.Errorf(`kocha: session: Path must be "/" with HostPrefix, but "/app"`)},
 		{&kocha.SessionMiddleware{
 			Name:  "test_session",
 			Store: &NullSessionStore{},
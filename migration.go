@@ -0,0 +1,339 @@
+package kocha
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+const (
+	// MigrationTableName is the name of the table that stores versions of
+	// the applied migrations.
+	MigrationTableName = "schema_migration"
+
+	// MigrationVersionZero is the version that represents the state before
+	// any migrations are applied. It can be passed to Migration.DownTo.
+	MigrationVersionZero = "0"
+)
+
+var (
+	migrationMethodRegexp = regexp.MustCompile(`^(Up|Down)_(\d{14})_(\w+)$`)
+	sqlTxType             = reflect.TypeOf((*sql.Tx)(nil))
+	errorType             = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// DatabaseConfig represents a configuration of the database.
+type DatabaseConfig struct {
+	Driver string // driver name for database/sql.
+	DSN    string // data source name.
+}
+
+// MigrationStatus represents a status of the migration.
+type MigrationStatus struct {
+	Version string // version of the migration. (timestamp of "20060102150405" format)
+	Name    string // name of the migration.
+	Applied bool   // whether the migration has been applied.
+}
+
+// Migration represents a runner of the migrations.
+//
+// Each migration is a pair of methods of the value given to Migrate.
+// The names of the methods must be "Up_VERSION_NAME" and "Down_VERSION_NAME",
+// where VERSION is a timestamp of "20060102150405" format. Their signatures
+// must be func(tx *sql.Tx) error.
+// The versions of the applied migrations are stored in MigrationTableName
+// table.
+type Migration struct {
+	// Writer is the destination of the progress of the migrations.
+	// If nil, the progress is not written.
+	Writer io.Writer
+
+	config DatabaseConfig
+	m      interface{}
+}
+
+// Migrate returns a new Migration.
+func Migrate(config DatabaseConfig, m interface{}) *Migration {
+	return &Migration{
+		config: config,
+		m:      m,
+	}
+}
+
+// Up applies the pending migrations in ascending order of versions.
+// If limit is negative, Up applies all pending migrations.
+func (mig *Migration) Up(limit int) error {
+	return mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		n := 0
+		for _, info := range infos {
+			if limit >= 0 && n >= limit {
+				break
+			}
+			if applied[info.version] {
+				continue
+			}
+			if err := mig.up(db, info); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+// Down rolls back the applied migrations in descending order of versions.
+// If limit is negative, Down rolls back all applied migrations.
+func (mig *Migration) Down(limit int) error {
+	return mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		n := 0
+		for i := len(infos) - 1; i >= 0; i-- {
+			if limit >= 0 && n >= limit {
+				break
+			}
+			if !applied[infos[i].version] {
+				continue
+			}
+			if err := mig.down(db, infos[i]); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+// Redo rolls back the latest applied migration and then applies it again.
+func (mig *Migration) Redo() error {
+	return mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		for i := len(infos) - 1; i >= 0; i-- {
+			if !applied[infos[i].version] {
+				continue
+			}
+			if err := mig.down(db, infos[i]); err != nil {
+				return err
+			}
+			return mig.up(db, infos[i])
+		}
+		return fmt.Errorf("kocha: migrate: no applied migration")
+	})
+}
+
+// UpTo applies the pending migrations that are older than or equal to
+// version in ascending order of versions.
+func (mig *Migration) UpTo(version string) error {
+	return mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		if !mig.hasVersion(infos, version) {
+			return fmt.Errorf("kocha: migrate: version %v is not found", version)
+		}
+		for _, info := range infos {
+			if info.version <= version && !applied[info.version] {
+				if err := mig.up(db, info); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DownTo rolls back the applied migrations that are newer than version in
+// descending order of versions.
+// If version is MigrationVersionZero, DownTo rolls back all applied
+// migrations.
+func (mig *Migration) DownTo(version string) error {
+	return mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		if version != MigrationVersionZero && !mig.hasVersion(infos, version) {
+			return fmt.Errorf("kocha: migrate: version %v is not found", version)
+		}
+		for i := len(infos) - 1; i >= 0; i-- {
+			if info := infos[i]; info.version > version && applied[info.version] {
+				if err := mig.down(db, info); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the statuses of all migrations in ascending order of
+// versions.
+func (mig *Migration) Status() (statuses []MigrationStatus, err error) {
+	err = mig.run(func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error {
+		for _, info := range infos {
+			statuses = append(statuses, MigrationStatus{
+				Version: info.version,
+				Name:    info.name,
+				Applied: applied[info.version],
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func (mig *Migration) run(f func(db *sql.DB, infos []*migrationInfo, applied map[string]bool) error) error {
+	infos, err := mig.collectInfos()
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(mig.config.Driver, mig.config.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := mig.createTableIfNotExists(db); err != nil {
+		return err
+	}
+	applied, err := mig.appliedVersions(db)
+	if err != nil {
+		return err
+	}
+	for version := range applied {
+		if !mig.hasVersion(infos, version) {
+			return fmt.Errorf("kocha: migrate: version %v has been applied, but the migration is not found", version)
+		}
+	}
+	return f(db, infos, applied)
+}
+
+func (mig *Migration) up(db *sql.DB, info *migrationInfo) error {
+	mig.printf("%10s %s_%s\n", "up", info.version, info.name)
+	return mig.transaction(db, info.up, fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", MigrationTableName, mig.placeholder()), info.version)
+}
+
+func (mig *Migration) down(db *sql.DB, info *migrationInfo) error {
+	mig.printf("%10s %s_%s\n", "down", info.version, info.name)
+	if !info.down.IsValid() {
+		return fmt.Errorf("kocha: migrate: %v_%v is irreversible because Down_%v_%v is undefined", info.version, info.name, info.version, info.name)
+	}
+	return mig.transaction(db, info.down, fmt.Sprintf("DELETE FROM %s WHERE version = %s", MigrationTableName, mig.placeholder()), info.version)
+}
+
+// printf writes the progress to mig.Writer if it isn't nil.
+func (mig *Migration) printf(format string, a ...interface{}) {
+	if mig.Writer != nil {
+		fmt.Fprintf(mig.Writer, format, a...)
+	}
+}
+
+func (mig *Migration) transaction(db *sql.DB, fn reflect.Value, query string, version string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if rv := fn.Call([]reflect.Value{reflect.ValueOf(tx)})[0]; !rv.IsNil() {
+		return rv.Interface().(error)
+	}
+	if _, err := tx.Exec(query, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (mig *Migration) createTableIfNotExists(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version VARCHAR(255) NOT NULL PRIMARY KEY)", MigrationTableName))
+	return err
+}
+
+func (mig *Migration) appliedVersions(db *sql.DB) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version FROM %s", MigrationTableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func (mig *Migration) placeholder() string {
+	switch mig.config.Driver {
+	case "postgres", "pgx":
+		return "$1"
+	}
+	return "?"
+}
+
+func (mig *Migration) hasVersion(infos []*migrationInfo, version string) bool {
+	for _, info := range infos {
+		if info.version == version {
+			return true
+		}
+	}
+	return false
+}
+
+type migrationInfo struct {
+	version string
+	name    string
+	up      reflect.Value
+	down    reflect.Value
+}
+
+// collectInfos returns the migrations that are sorted by version.
+func (mig *Migration) collectInfos() ([]*migrationInfo, error) {
+	if mig.m == nil {
+		return nil, fmt.Errorf("kocha: migrate: migration is nil")
+	}
+	v := reflect.ValueOf(mig.m)
+	t := v.Type()
+	infoMap := make(map[string]*migrationInfo)
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		matches := migrationMethodRegexp.FindStringSubmatch(method.Name)
+		if matches == nil {
+			continue
+		}
+		if mt := method.Type; mt.NumIn() != 2 || mt.In(1) != sqlTxType || mt.NumOut() != 1 || mt.Out(0) != errorType {
+			return nil, fmt.Errorf("kocha: migrate: %T.%s must be func(tx *sql.Tx) error", mig.m, method.Name)
+		}
+		direction, version, name := matches[1], matches[2], matches[3]
+		info := infoMap[version]
+		if info == nil {
+			info = &migrationInfo{version: version, name: name}
+			infoMap[version] = info
+		}
+		if info.name != name {
+			return nil, fmt.Errorf("kocha: migrate: version %v is duplicated: %v and %v", version, info.name, name)
+		}
+		switch direction {
+		case "Up":
+			info.up = v.Method(i)
+		case "Down":
+			info.down = v.Method(i)
+		}
+	}
+	infos := make([]*migrationInfo, 0, len(infoMap))
+	for _, info := range infoMap {
+		if !info.up.IsValid() {
+			return nil, fmt.Errorf("kocha: migrate: Up_%v_%v is undefined", info.version, info.name)
+		}
+		infos = append(infos, info)
+	}
+	sort.Sort(migrationInfos(infos))
+	return infos, nil
+}
+
+type migrationInfos []*migrationInfo
+
+func (infos migrationInfos) Len() int           { return len(infos) }
+func (infos migrationInfos) Less(i, j int) bool { return infos[i].version < infos[j].version }
+func (infos migrationInfos) Swap(i, j int)      { infos[i], infos[j] = infos[j], infos[i] }
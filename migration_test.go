@@ -0,0 +1,375 @@
+package kocha_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/naoina/kocha"
+)
+
+// testMigrationDriver is a database/sql driver that records executed queries
+// and stores versions of MigrationTableName in memory.
+type testMigrationDriver struct {
+	mu       sync.Mutex
+	versions map[string]bool
+	queries  []string
+}
+
+func (d *testMigrationDriver) Open(name string) (driver.Conn, error) {
+	return &testMigrationConn{d: d}, nil
+}
+
+func (d *testMigrationDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.versions = make(map[string]bool)
+	d.queries = nil
+}
+
+type testMigrationConn struct {
+	d *testMigrationDriver
+}
+
+func (c *testMigrationConn) Prepare(query string) (driver.Stmt, error) {
+	return &testMigrationStmt{d: c.d, query: query}, nil
+}
+
+func (c *testMigrationConn) Close() error              { return nil }
+func (c *testMigrationConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *testMigrationConn) Commit() error             { return nil }
+func (c *testMigrationConn) Rollback() error           { return nil }
+
+type testMigrationStmt struct {
+	d     *testMigrationDriver
+	query string
+}
+
+func (s *testMigrationStmt) Close() error  { return nil }
+func (s *testMigrationStmt) NumInput() int { return -1 }
+
+func (s *testMigrationStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS "+kocha.MigrationTableName):
+	case strings.HasPrefix(s.query, "INSERT INTO "+kocha.MigrationTableName):
+		s.d.versions[args[0].(string)] = true
+	case strings.HasPrefix(s.query, "DELETE FROM "+kocha.MigrationTableName):
+		delete(s.d.versions, args[0].(string))
+	default:
+		s.d.queries = append(s.d.queries, s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *testMigrationStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	var versions []string
+	for v := range s.d.versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return &testMigrationRows{versions: versions}, nil
+}
+
+type testMigrationRows struct {
+	versions []string
+}
+
+func (r *testMigrationRows) Columns() []string { return []string{"version"} }
+func (r *testMigrationRows) Close() error      { return nil }
+
+func (r *testMigrationRows) Next(dest []driver.Value) error {
+	if len(r.versions) == 0 {
+		return io.EOF
+	}
+	dest[0], r.versions = r.versions[0], r.versions[1:]
+	return nil
+}
+
+var testMigrationDB = &testMigrationDriver{}
+
+func init() {
+	sql.Register("kocha_test_migration", testMigrationDB)
+}
+
+type testMigration struct{}
+
+func (m *testMigration) Up_20140101000000_CreateUsers(tx *sql.Tx) error {
+	_, err := tx.Exec("up create_users")
+	return err
+}
+
+func (m *testMigration) Down_20140101000000_CreateUsers(tx *sql.Tx) error {
+	_, err := tx.Exec("down create_users")
+	return err
+}
+
+func (m *testMigration) Up_20140102000000_AddEmailToUsers(tx *sql.Tx) error {
+	_, err := tx.Exec("up add_email_to_users")
+	return err
+}
+
+func (m *testMigration) Down_20140102000000_AddEmailToUsers(tx *sql.Tx) error {
+	_, err := tx.Exec("down add_email_to_users")
+	return err
+}
+
+func (m *testMigration) Up_20140103000000_CreatePosts(tx *sql.Tx) error {
+	_, err := tx.Exec("up create_posts")
+	return err
+}
+
+func (m *testMigration) Down_20140103000000_CreatePosts(tx *sql.Tx) error {
+	_, err := tx.Exec("down create_posts")
+	return err
+}
+
+func newTestMigration() *kocha.Migration {
+	testMigrationDB.reset()
+	return kocha.Migrate(kocha.DatabaseConfig{Driver: "kocha_test_migration"}, &testMigration{})
+}
+
+func withDiscardStdout(f func()) {
+	oldStdout := os.Stdout
+	w, err := os.OpenFile(os.DevNull, os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+	defer w.Close()
+	os.Stdout = w
+	defer func() {
+		os.Stdout = oldStdout
+	}()
+	f()
+}
+
+func TestMigration_Up(t *testing.T) {
+	for _, v := range []struct {
+		limit  int
+		expect []string
+	}{
+		{-1, []string{"up create_users", "up add_email_to_users", "up create_posts"}},
+		{1, []string{"up create_users"}},
+		{2, []string{"up create_users", "up add_email_to_users"}},
+		{0, nil},
+	} {
+		mig := newTestMigration()
+		var err error
+		withDiscardStdout(func() {
+			err = mig.Up(v.limit)
+		})
+		if err != nil {
+			t.Errorf("Migration.Up(%#v) => %#v; want nil", v.limit, err)
+			continue
+		}
+		actual := testMigrationDB.queries
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Migration.Up(%#v) => %#v; want %#v", v.limit, actual, expect)
+		}
+	}
+}
+
+func TestMigration_Writer(t *testing.T) {
+	mig := newTestMigration()
+	var buf bytes.Buffer
+	mig.Writer = &buf
+	if err := mig.Up(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mig.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	actual := buf.String()
+	expect := "        up 20140101000000_CreateUsers\n      down 20140101000000_CreateUsers\n"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Writer => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestMigration_Down(t *testing.T) {
+	for _, v := range []struct {
+		limit  int
+		expect []string
+	}{
+		{-1, []string{"down create_posts", "down add_email_to_users", "down create_users"}},
+		{1, []string{"down create_posts"}},
+		{2, []string{"down create_posts", "down add_email_to_users"}},
+	} {
+		mig := newTestMigration()
+		var err error
+		withDiscardStdout(func() {
+			if err = mig.Up(-1); err != nil {
+				return
+			}
+			testMigrationDB.queries = nil
+			err = mig.Down(v.limit)
+		})
+		if err != nil {
+			t.Errorf("Migration.Down(%#v) => %#v; want nil", v.limit, err)
+			continue
+		}
+		actual := testMigrationDB.queries
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Migration.Down(%#v) => %#v; want %#v", v.limit, actual, expect)
+		}
+	}
+}
+
+func TestMigration_Redo(t *testing.T) {
+	mig := newTestMigration()
+	var err error
+	withDiscardStdout(func() {
+		err = mig.Redo()
+	})
+	var actual interface{} = err
+	var expect interface{} = fmt.Errorf("kocha: migrate: no applied migration")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Redo() => %#v; want %#v", actual, expect)
+	}
+
+	withDiscardStdout(func() {
+		if err = mig.Up(2); err != nil {
+			return
+		}
+		testMigrationDB.queries = nil
+		err = mig.Redo()
+	})
+	if err != nil {
+		t.Fatalf("Migration.Redo() => %#v; want nil", err)
+	}
+	actual = testMigrationDB.queries
+	expect = []string{"down add_email_to_users", "up add_email_to_users"}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Redo() => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestMigration_UpTo(t *testing.T) {
+	mig := newTestMigration()
+	var err error
+	withDiscardStdout(func() {
+		err = mig.UpTo("20140102000000")
+	})
+	if err != nil {
+		t.Fatalf("Migration.UpTo(%#v) => %#v; want nil", "20140102000000", err)
+	}
+	var actual interface{} = testMigrationDB.queries
+	var expect interface{} = []string{"up create_users", "up add_email_to_users"}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.UpTo(%#v) => %#v; want %#v", "20140102000000", actual, expect)
+	}
+
+	withDiscardStdout(func() {
+		err = mig.UpTo("20991231000000")
+	})
+	actual = err
+	expect = fmt.Errorf("kocha: migrate: version %v is not found", "20991231000000")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.UpTo(%#v) => %#v; want %#v", "20991231000000", actual, expect)
+	}
+}
+
+func TestMigration_DownTo(t *testing.T) {
+	for _, v := range []struct {
+		version string
+		expect  []string
+	}{
+		{"20140101000000", []string{"down create_posts", "down add_email_to_users"}},
+		{"20140103000000", nil},
+		{kocha.MigrationVersionZero, []string{"down create_posts", "down add_email_to_users", "down create_users"}},
+	} {
+		mig := newTestMigration()
+		var err error
+		withDiscardStdout(func() {
+			if err = mig.Up(-1); err != nil {
+				return
+			}
+			testMigrationDB.queries = nil
+			err = mig.DownTo(v.version)
+		})
+		if err != nil {
+			t.Errorf("Migration.DownTo(%#v) => %#v; want nil", v.version, err)
+			continue
+		}
+		actual := testMigrationDB.queries
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Migration.DownTo(%#v) => %#v; want %#v", v.version, actual, expect)
+		}
+	}
+}
+
+func TestMigration_Status(t *testing.T) {
+	mig := newTestMigration()
+	var (
+		statuses []kocha.MigrationStatus
+		err      error
+	)
+	withDiscardStdout(func() {
+		if err = mig.Up(1); err != nil {
+			return
+		}
+		statuses, err = mig.Status()
+	})
+	if err != nil {
+		t.Fatalf("Migration.Status() => (_, %#v); want (_, nil)", err)
+	}
+	actual := statuses
+	expect := []kocha.MigrationStatus{
+		{Version: "20140101000000", Name: "CreateUsers", Applied: true},
+		{Version: "20140102000000", Name: "AddEmailToUsers", Applied: false},
+		{Version: "20140103000000", Name: "CreatePosts", Applied: false},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Status() => (%#v, nil); want (%#v, nil)", actual, expect)
+	}
+}
+
+type testInvalidMigration struct{}
+
+func (m *testInvalidMigration) Up_20140101000000_CreateUsers(tx *sql.DB) error {
+	return nil
+}
+
+type testIrreversibleMigration struct{}
+
+func (m *testIrreversibleMigration) Up_20140101000000_CreateUsers(tx *sql.Tx) error {
+	return nil
+}
+
+func TestMigration_withInvalidMigration(t *testing.T) {
+	testMigrationDB.reset()
+	mig := kocha.Migrate(kocha.DatabaseConfig{Driver: "kocha_test_migration"}, &testInvalidMigration{})
+	err := mig.Up(-1)
+	var actual interface{} = err
+	var expect interface{} = fmt.Errorf("kocha: migrate: %T.%s must be func(tx *sql.Tx) error", &testInvalidMigration{}, "Up_20140101000000_CreateUsers")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Up(-1) => %#v; want %#v", actual, expect)
+	}
+
+	mig = kocha.Migrate(kocha.DatabaseConfig{Driver: "kocha_test_migration"}, &testIrreversibleMigration{})
+	withDiscardStdout(func() {
+		if err = mig.Up(-1); err != nil {
+			return
+		}
+		err = mig.Down(-1)
+	})
+	actual = err
+	expect = fmt.Errorf("kocha: migrate: %v_%v is irreversible because Down_%v_%v is undefined", "20140101000000", "CreateUsers", "20140101000000", "CreateUsers")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migration.Down(-1) => %#v; want %#v", actual, expect)
+	}
+}
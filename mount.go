@@ -0,0 +1,70 @@
+package kocha
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mountPathParam is the name of the wildcard parameter of the mount point.
+const mountPathParam = "_kocha_mount_path"
+
+// Mount returns a RouteTable that mounts app under prefix.
+//
+// The requests to prefix and the paths under prefix are passed to app with
+// the paths that prefix is stripped. app processes the requests by its own
+// middlewares, routes and templates, and Reverse of app returns the URLs that
+// are prefixed with prefix.
+// The returned routes should be appended to the RouteTable of the parent
+// application.
+// An application can be mounted only once because Reverse of app has only one
+// prefix. Mount panics if app has already been mounted.
+//
+//	AppConfig.RouteTable = kocha.RouteTable{
+//	    {Name: "root", Path: "/", Controller: &controller.Root{}},
+//	}.Append(kocha.Mount("admin", "/admin", admin.App))
+func Mount(name, prefix string, app *Application) RouteTable {
+	prefix = strings.TrimRight(prefix, "/")
+	if app.Router.mounted {
+		panic(fmt.Errorf("kocha: mount: %v: application has already been mounted at %q", name, app.Router.prefix))
+	}
+	app.Router.prefix, app.Router.mounted = prefix, true
+	root := prefix
+	if root == "" {
+		root = "/"
+	}
+	return RouteTable{
+		{
+			Name:       name,
+			Path:       root,
+			Controller: &mountController{app: app},
+		},
+		{
+			Path:       prefix + "/*" + mountPathParam,
+			Controller: &mountController{app: app, param: mountPathParam},
+		},
+	}
+}
+
+// mountController is a controller that passes the requests to the mounted
+// application.
+type mountController struct {
+	app   *Application
+	param string // name of the wildcard parameter. Empty for the mount point itself.
+}
+
+func (m *mountController) GET(c *Context) error    { return m.serve(c) }
+func (m *mountController) POST(c *Context) error   { return m.serve(c) }
+func (m *mountController) PUT(c *Context) error    { return m.serve(c) }
+func (m *mountController) DELETE(c *Context) error { return m.serve(c) }
+func (m *mountController) HEAD(c *Context) error   { return m.serve(c) }
+func (m *mountController) PATCH(c *Context) error  { return m.serve(c) }
+
+func (m *mountController) serve(c *Context) error {
+	path := "/"
+	if m.param != "" {
+		path += c.Params.Get(m.param)
+		c.Params.Del(m.param)
+	}
+	serveHTTPWithPath(m.app, c, path)
+	return nil
+}
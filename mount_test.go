@@ -0,0 +1,115 @@
+package kocha_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/kocha"
+)
+
+func newTestMountApp(t *testing.T) (parent, sub *kocha.Application) {
+	sub = kocha.NewTestApp()
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			return c.RenderText("parent root")
+		}},
+	}.Append(kocha.Mount("sub", "/sub/", sub))
+	parent, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parent, sub
+}
+
+func TestMount(t *testing.T) {
+	parent, _ := newTestMountApp(t)
+	for _, v := range []struct {
+		method string
+		uri    string
+		status int
+		body   string
+	}{
+		{"GET", "/", http.StatusOK, "parent root"},
+		{"GET", "/sub", http.StatusOK, "This is layout\nThis is root\n\n"},
+		{"GET", "/sub/", http.StatusOK, "This is layout\nThis is root\n\n"},
+		{"GET", "/sub/user/7", http.StatusOK, "This is layout\nThis is user 7\n\n"},
+		{"GET", "/sub/missing", http.StatusNotFound, "This is layout\n404 template not found\n\n"},
+		{"POST", "/sub/user/7", http.StatusMethodNotAllowed, "This is layout\n405 method not allowed\n\n"},
+		{"OPTIONS", "/sub/user/7", http.StatusOK, ""},
+		{"GET", "/missing", http.StatusNotFound, ""},
+	} {
+		req, err := http.NewRequest(v.method, v.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		parent.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = v.status
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v status => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+		if v.body == "" {
+			continue
+		}
+		actual = w.Body.String()
+		expect = v.body
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+	}
+}
+
+func TestMount_Reverse(t *testing.T) {
+	parent, sub := newTestMountApp(t)
+	for _, v := range []struct {
+		app    *kocha.Application
+		name   string
+		args   []interface{}
+		expect string
+	}{
+		{parent, "root", nil, "/"},
+		{parent, "sub", nil, "/sub"},
+		{sub, "root", nil, "/sub"},
+		{sub, "user", []interface{}{7}, "/sub/user/7"},
+	} {
+		r, err := v.app.Router.Reverse(v.name, v.args...)
+		if err != nil {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (_, %#v); want (_, nil)`, v.name, v.args, err)
+			continue
+		}
+		actual := r
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (%#v, nil); want (%#v, nil)`, v.name, v.args, actual, expect)
+		}
+	}
+}
+
+func TestMount_twice(t *testing.T) {
+	_, sub := newTestMountApp(t)
+	defer func() {
+		var actual interface{} = recover()
+		var expect interface{} = fmt.Errorf(`kocha: mount: other: application has already been mounted at "/sub"`)
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Mount("other", "/other", sub) => panic(%#v); want panic(%#v)`, actual, expect)
+		}
+		r, err := sub.Router.Reverse("root")
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = r
+		expect = "/sub"
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v) => (%#v, nil); want (%#v, nil)`, "root", actual, expect)
+		}
+	}()
+	kocha.Mount("other", "/other", sub)
+}
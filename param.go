@@ -2,18 +2,27 @@ package kocha
 
 import (
 	"database/sql"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"net/mail"
 	"net/url"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/naoina/kocha/util"
+	"github.com/ugorji/go/codec"
 )
 
 var (
@@ -45,7 +54,9 @@ func (e *ParamError) Error() string {
 	return fmt.Sprintf("%v is %v", e.Name, e.Err)
 }
 
-var formTimeFormats = []string{
+// DefaultTimeFormats is the default layouts of time.Time form values.
+// This can be overridden by setting Config.TimeFormats.
+var DefaultTimeFormats = []string{
 	"2006-01-02 15:04:05",
 	"2006/01/02 15:04:05",
 	"2006-01-02T15:04:05",
@@ -59,11 +70,47 @@ var formTimeFormats = []string{
 	"20060102",
 }
 
+// ParamBinder is a function that parses a form value to a value of a type.
+// The returned value must be assignable to the type that the ParamBinder is
+// registered for.
+type ParamBinder func(s string) (interface{}, error)
+
+type paramBinders map[reflect.Type]ParamBinder
+
+// ParamBinders is relation between type and ParamBinder.
+// Params.Bind uses the ParamBinder of the type of a field in preference to
+// the built-in parsers. The error returned from ParamBinder is set to
+// Context.Errors as is.
+//
+//	kocha.ParamBinders.Set(reflect.TypeOf(Status(0)), func(s string) (interface{}, error) {
+//	    return ParseStatus(s)
+//	})
+var ParamBinders = paramBinders{}
+
+// Get returns the ParamBinder of the type.
+func (b paramBinders) Get(t reflect.Type) ParamBinder {
+	return b[t]
+}
+
+// Set set the ParamBinder to the type.
+func (b paramBinders) Set(t reflect.Type, binder ParamBinder) {
+	b[t] = binder
+}
+
+// Del delete the ParamBinder of the type.
+func (b paramBinders) Del(t reflect.Type) {
+	delete(b, t)
+}
+
 // Params represents a form values.
 type Params struct {
 	c *Context
 	url.Values
 	prefix string
+
+	// canonical reports whether the numbers of Values are formatted by
+	// strconv regardless of the number format.
+	canonical bool
 }
 
 func newParams(c *Context, values url.Values, prefix string) *Params {
@@ -71,6 +118,7 @@ func newParams(c *Context, values url.Values, prefix string) *Params {
 	p.c = c
 	p.Values = values
 	p.prefix = prefix
+	p.canonical = false
 	return p
 }
 
@@ -83,6 +131,67 @@ func (params *Params) From(name string, children ...string) *Params {
 // obj must be a pointer of struct. If obj isn't a pointer of struct, it returns error.
 // Note that it in the case of errors due to a form value binding error, no error is returned.
 // Binding errors will set to map of returned from Controller.Errors().
+//
+// The form value of a field is looked up by the name in the prefix of
+// params. e.g. "user.name" for Params.From("user").Bind(obj, "name").
+// A field is bound from a form value by the ParamBinder in ParamBinders if
+// registered for the type of the field. Otherwise, sql.Scanner,
+// encoding.TextUnmarshaler and the built-in parsers for the basic types and
+// time.Time are used in order.
+// Fields of the following types are bound from multiple form values.
+//
+//	struct      each exported field from "name.field_name". The fields of the
+//	            embedded struct are bound as the fields of the struct.
+//	[]T         all values of "name", or "name[0]", "name[1]", ... in order
+//	            of the index. Sparse indexes are compacted. "name.field" is
+//	            bound as the first element if T is a struct.
+//	map[K]T     "name[k]" or "name.k" for each k. K must be a string type.
+//	*T          as T. A pointer is allocated only if the form values exist.
+//
+// T can also be any of above types. e.g. "items[0].name" is bound to
+// Name field of the first element of Items field.
+// *multipart.FileHeader and []*multipart.FileHeader fields are bound from
+// the uploaded files of the multipart form.
+//
+// time.Time is parsed by the layouts of the `time` struct tag that are
+// separated by "|", or Config.TimeFormats. Numbers are parsed by the format
+// of the `number` struct tag or Config.NumberFormat if specified. The format
+// is an example of the number such as "1,234.5" and "1.234,5". e.g.
+//
+//	type Order struct {
+//	    Date  time.Time `time:"02/01/2006|02/01/2006 15:04"`
+//	    Price float64   `number:"1.234,5"`
+//	}
+//
+// Bind also validates the fields of fieldNames by the rules of the `validate`
+// struct tag. The rules are separated by ",". e.g.
+//
+//	type User struct {
+//	    Name  string `validate:"required,min=3,max=50"`
+//	    Email string `validate:"required,email"`
+//	    Age   int    `validate:"min=0,max=150"`
+//	    Code  string `validate:"regexp=[a-z]+"`
+//	}
+//
+// The following rules are supported.
+//
+//	required     the form value must be given and not be empty.
+//	min=N        length of string, or number must be N or more.
+//	max=N        length of string, or number must be N or less.
+//	len=N        length of string must be N.
+//	email        string must be an email address.
+//	url          string must be an absolute URL.
+//	regexp=RE    string must match RE entirely. It must be the last rule
+//	             because RE can contain ",".
+//	maxsize=N    size of uploaded file must be N bytes or less. N can have
+//	             a suffix of KB, MB or GB.
+//	mimetype=T   MIME type of uploaded file must be T. T is separated by
+//	             "|", and can be a wildcard such as "image/*". The MIME type
+//	             is sniffed from the content of file.
+//
+// The rules except required are not evaluated if the form value is empty or
+// not given. Each failed rule is set to Context.Errors as a ParamError that
+// has a *ValidationError. If a struct tag is malformed, Bind returns error.
 func (params *Params) Bind(obj interface{}, fieldNames ...string) error {
 	rvalue := reflect.ValueOf(obj)
 	if rvalue.Kind() != reflect.Ptr {
@@ -104,24 +213,359 @@ func (params *Params) Bind(obj interface{}, fieldNames ...string) error {
 				filepath.Base(filename), line, name, rtype.Name(), util.ToCamelCase(name))
 			continue
 		}
-		fname := params.prefixedName(params.prefix, name)
-		values, found := params.Values[fname]
+		sf := rtype.FieldByIndex(index)
+		key := params.prefixedName(params.prefix, name)
+		if _, err := params.bind(key, name, rvalue.FieldByIndex(index), rtype, sf); err != nil {
+			return fmt.Errorf("kocha: Bind: %v", err)
+		}
+	}
+	return nil
+}
+
+// bind binds the form values of key to v that is the field sf of the struct
+// of owner, and then validates v by the `validate` struct tag of sf.
+// name is the key without the prefix of params, and it will be used as the
+// key of Context.Errors. ok is false if any binding errors are added to
+// Context.Errors. The validation is skipped in that case.
+func (params *Params) bind(key, name string, v reflect.Value, owner reflect.Type, sf reflect.StructField) (ok bool, err error) {
+	rules, err := parseValidationRules(sf.Tag.Get("validate"))
+	if err != nil {
+		return false, fmt.Errorf("%v.%v: %v", owner.Name(), sf.Name, err)
+	}
+	opts, err := params.bindOptions(sf.Tag)
+	if err != nil {
+		return false, fmt.Errorf("%v.%v: %v", owner.Name(), sf.Name, err)
+	}
+	empty, ok, err := params.bindValue(key, name, v, opts)
+	if err != nil || !ok {
+		return ok, err
+	}
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if err := params.validate(name, v, rules, empty); err != nil {
+		return false, fmt.Errorf("%v.%v: %v", owner.Name(), sf.Name, err)
+	}
+	return true, nil
+}
+
+// bindOptions represents the options of binding for a field.
+type bindOptions struct {
+	timeFormats  []string
+	numberFormat *numberFormat // nil if numbers are parsed by strconv.
+}
+
+// bindOptions returns the bindOptions from the `time` and `number` struct
+// tags and Config.
+func (params *Params) bindOptions(tag reflect.StructTag) (*bindOptions, error) {
+	opts := &bindOptions{timeFormats: DefaultTimeFormats}
+	var format string
+	if params.c != nil && params.c.App != nil {
+		if len(params.c.App.Config.TimeFormats) > 0 {
+			opts.timeFormats = params.c.App.Config.TimeFormats
+		}
+		format = params.c.App.Config.NumberFormat
+	}
+	if layouts := tag.Get("time"); layouts != "" {
+		opts.timeFormats = strings.Split(layouts, "|")
+	}
+	if f := tag.Get("number"); f != "" {
+		format = f
+	}
+	if format == "" || params.canonical {
+		return opts, nil
+	}
+	nf, err := parseNumberFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	opts.numberFormat = nf
+	return opts, nil
+}
+
+// bindValue binds the form values of key to v.
+// empty reports whether the form values of key are empty or not given, and
+// ok is false if any binding errors are added to Context.Errors.
+// See Bind for the rules of binding.
+func (params *Params) bindValue(key, name string, v reflect.Value, opts *bindOptions) (empty, ok bool, err error) {
+	switch {
+	case v.Type() == fileHeaderType || v.Type() == fileHeadersType:
+		return params.bindFiles(key, v)
+	case isBindableScalar(v):
+		values, found := params.Values[key]
 		if !found {
+			return true, true, nil
+		}
+		ok, err := params.set(name, v, values[0], opts)
+		return values[0] == "", ok, err
+	case v.Kind() == reflect.Ptr:
+		if !params.hasValues(key) {
+			return true, true, nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return params.bindValue(key, name, v.Elem(), opts)
+	case v.Kind() == reflect.Slice:
+		return params.bindSlice(key, name, v, opts)
+	case v.Kind() == reflect.Map:
+		return params.bindMap(key, name, v, opts)
+	}
+	return params.bindStruct(key, name, v)
+}
+
+func (params *Params) bindSlice(key, name string, v reflect.Value, opts *bindOptions) (empty, ok bool, err error) {
+	if values, found := params.Values[key]; found && isBindableScalar(reflect.New(v.Type().Elem()).Elem()) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		ok = true
+		for i, value := range values {
+			elemOK, err := params.set(name, slice.Index(i), value, opts)
+			if err != nil {
+				return false, false, err
+			}
+			ok = ok && elemOK
+		}
+		if ok {
+			v.Set(slice)
+		}
+		return len(values) == 0, ok, nil
+	}
+	subkeys := params.subkeys(key, false)
+	if len(subkeys) == 0 {
+		if len(params.subkeys(key, true)) == 0 {
+			return true, true, nil
+		}
+		// "key.field" is bound as the first element.
+		slice := reflect.MakeSlice(v.Type(), 1, 1)
+		if _, ok, err = params.bindValue(key, name, slice.Index(0), opts); ok && err == nil {
+			v.Set(slice)
+		}
+		return false, ok, err
+	}
+	indexes := make([]int, 0, len(subkeys))
+	for _, subkey := range subkeys {
+		i, err := strconv.Atoi(subkey)
+		if err != nil || i < 0 {
+			params.addError(name, ErrInvalidFormat)
+			return false, false, nil
+		}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	slice := reflect.MakeSlice(v.Type(), len(indexes), len(indexes))
+	ok = true
+	for i, index := range indexes {
+		sub := fmt.Sprintf("[%d]", index)
+		_, elemOK, err := params.bindValue(key+sub, name+sub, slice.Index(i), opts)
+		if err != nil {
+			return false, false, err
+		}
+		ok = ok && elemOK
+	}
+	if ok {
+		v.Set(slice)
+	}
+	return false, ok, nil
+}
+
+func (params *Params) bindMap(key, name string, v reflect.Value, opts *bindOptions) (empty, ok bool, err error) {
+	if v.Type().Key().Kind() != reflect.String {
+		params.c.App.Logger.Warnf("kocha: Bind: unsupported map key type: %v", v.Type().Key())
+		params.addError(name, ErrUnsupportedFieldType)
+		return false, false, nil
+	}
+	bracketed, dotted := params.subkeys(key, false), params.subkeys(key, true)
+	if len(bracketed) == 0 && len(dotted) == 0 {
+		return true, true, nil
+	}
+	m := v
+	if m.IsNil() {
+		m = reflect.MakeMap(v.Type())
+	}
+	ok = true
+	for i, subkey := range append(bracketed, dotted...) {
+		sub := "[" + subkey + "]"
+		if i >= len(bracketed) {
+			sub = "." + subkey
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		_, elemOK, err := params.bindValue(key+sub, name+sub, elem, opts)
+		if err != nil {
+			return false, false, err
+		}
+		if elemOK {
+			m.SetMapIndex(reflect.ValueOf(subkey).Convert(v.Type().Key()), elem)
+		}
+		ok = ok && elemOK
+	}
+	v.Set(m)
+	return false, ok, nil
+}
+
+func (params *Params) bindStruct(key, name string, v reflect.Value) (empty, ok bool, err error) {
+	if !params.hasValues(key) {
+		return true, true, nil
+	}
+	ok = true
+	rtype := v.Type()
+	for i := 0; i < rtype.NumField(); i++ {
+		sf := rtype.Field(i)
+		if util.IsUnexportedField(sf) {
+			continue
+		}
+		fieldKey, fieldName := key, name
+		if !sf.Anonymous {
+			fieldKey = params.prefixedName(key, util.ToSnakeCase(sf.Name))
+			fieldName = params.prefixedName(name, util.ToSnakeCase(sf.Name))
+		}
+		fieldOK, err := params.bind(fieldKey, fieldName, v.Field(i), rtype, sf)
+		if err != nil {
+			return false, false, err
+		}
+		ok = ok && fieldOK
+	}
+	return false, ok, nil
+}
+
+// set parses s and sets it to v. It returns false if the parsing fails,
+// and then the error is added to Context.Errors.
+//
+// s is parsed by the first one of the followings that is available for the
+// type of v: the binder in ParamBinders, sql.Scanner,
+// encoding.TextUnmarshaler and the built-in parser.
+func (params *Params) set(name string, v reflect.Value, s string, opts *bindOptions) (ok bool, err error) {
+	if binder := ParamBinders.Get(v.Type()); binder != nil {
+		value, err := binder(s)
+		if err != nil {
+			params.addError(name, err)
+			return false, nil
+		}
+		rv := reflect.ValueOf(value)
+		if !rv.IsValid() || !rv.Type().AssignableTo(v.Type()) {
+			return false, fmt.Errorf("binder for %v returns %T", v.Type(), value)
+		}
+		v.Set(rv)
+		return true, nil
+	}
+	if unmarshal := unmarshalFunc(v); unmarshal != nil {
+		if err := unmarshal(s); err != nil {
+			params.c.App.Logger.Warnf("kocha: Bind: %v", err)
+			params.addError(name, ErrInvalidFormat)
+			return false, nil
+		}
+		return true, nil
+	}
+	value, err := params.parse(v.Interface(), s, opts)
+	if err != nil {
+		params.addError(name, err)
+		return false, nil
+	}
+	v.Set(reflect.ValueOf(value))
+	return true, nil
+}
+
+// hasValues returns whether the form values of key or the children of key
+// exist.
+func (params *Params) hasValues(key string) bool {
+	for k := range params.Values {
+		if k == key || strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	for k := range params.files() {
+		if k == key || strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// subkeys returns the sorted unique subkeys of key in the form of
+// "key[subkey]", or "key.subkey" if dotted is true.
+func (params *Params) subkeys(key string, dotted bool) []string {
+	prefix, terminators := key+"[", "]"
+	if dotted {
+		prefix, terminators = key+".", ".["
+	}
+	seen := map[string]bool{}
+	var subkeys []string
+	for k := range params.Values {
+		if !strings.HasPrefix(k, prefix) {
 			continue
 		}
-		field := rvalue.FieldByIndex(index)
-		for field.Kind() == reflect.Ptr {
-			field = field.Elem()
+		rest := k[len(prefix):]
+		i := strings.IndexAny(rest, terminators)
+		switch {
+		case i >= 0:
+			rest = rest[:i]
+		case !dotted:
+			continue
+		}
+		if seen[rest] {
+			continue
 		}
-		value, err := params.parse(field.Interface(), values[0])
+		seen[rest] = true
+		subkeys = append(subkeys, rest)
+	}
+	sort.Strings(subkeys)
+	return subkeys
+}
+
+// isBindableScalar returns whether v is bound from a single form value.
+func isBindableScalar(v reflect.Value) bool {
+	if ParamBinders.Get(v.Type()) != nil || unmarshalFunc(v) != nil {
+		return true
+	}
+	switch v.Interface().(type) {
+	case time.Time, []byte:
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Struct:
+		return false
+	}
+	return true
+}
+
+// unmarshalFunc returns a function that unmarshals a form value to v if v
+// implements sql.Scanner or encoding.TextUnmarshaler. Otherwise, it returns
+// nil. time.Time is excluded because it's parsed with the form time formats.
+func unmarshalFunc(v reflect.Value) func(s string) error {
+	if _, isTime := v.Interface().(time.Time); isTime || !v.CanAddr() {
+		return nil
+	}
+	switch t := v.Addr().Interface().(type) {
+	case sql.Scanner:
+		return func(s string) error { return t.Scan(s) }
+	case encoding.TextUnmarshaler:
+		return func(s string) error { return t.UnmarshalText([]byte(s)) }
+	}
+	return nil
+}
+
+// validate validates field by rules, and then adds the errors of the failed
+// rules to Context.Errors. empty reports whether the form value of field is
+// empty or not given.
+func (params *Params) validate(name string, field reflect.Value, rules []*validationRule, empty bool) error {
+	for _, rule := range rules {
+		if empty && rule.name != "required" {
+			continue
+		}
+		verr, err := rule.validate(field, empty)
 		if err != nil {
-			params.c.Errors[name] = append(params.c.Errors[name], NewParamError(name, err))
+			return err
+		}
+		if verr != nil {
+			params.addError(name, verr)
 		}
-		field.Set(reflect.ValueOf(value))
 	}
 	return nil
 }
 
+func (params *Params) addError(name string, err error) {
+	params.c.Errors[name] = append(params.c.Errors[name], NewParamError(name, err))
+}
+
 func (params *Params) prefixedName(prefix string, names ...string) string {
 	if prefix != "" {
 		names = append([]string{prefix}, names...)
@@ -158,18 +602,29 @@ func (params *Params) findFieldIndex(rtype reflect.Type, name string, index []in
 	return nil
 }
 
-func (params *Params) parse(fv interface{}, vStr string) (value interface{}, err error) {
+func (params *Params) parse(fv interface{}, vStr string, opts *bindOptions) (value interface{}, err error) {
+	switch fv.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		if opts.numberFormat != nil {
+			if vStr, err = opts.numberFormat.normalize(vStr); err != nil {
+				params.c.App.Logger.Warnf("kocha: Bind: %v", err)
+				return nil, ErrInvalidFormat
+			}
+		}
+	}
 	switch t := fv.(type) {
 	case sql.Scanner:
 		err = t.Scan(vStr)
 	case time.Time:
-		for _, format := range formTimeFormats {
+		for _, format := range opts.timeFormats {
 			if value, err = time.Parse(format, vStr); err == nil {
 				break
 			}
 		}
 	case string:
 		value = vStr
+	case []byte:
+		value = []byte(vStr)
 	case bool:
 		value, err = strconv.ParseBool(vStr)
 	case int, int8, int16, int32, int64:
@@ -198,8 +653,418 @@ func (params *Params) parse(fv interface{}, vStr string) (value interface{}, err
 	return value, nil
 }
 
+// ValidationError indicates that a field doesn't satisfy a rule of the
+// `validate` struct tag.
+type ValidationError struct {
+	Rule  string // name of the rule. e.g. "min".
+	Param string // parameter of the rule. e.g. "3" of "min=3".
+
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+var (
+	validationRulesCache   = map[string][]*validationRule{}
+	validationRulesCacheMu sync.RWMutex
+)
+
+// validationRule represents a rule of the `validate` struct tag.
+type validationRule struct {
+	name      string
+	param     string
+	n         float64        // parameter of min, max, len and maxsize.
+	re        *regexp.Regexp // parameter of regexp.
+	mimeTypes []string       // parameter of mimetype.
+}
+
+// parseValidationRules parses the `validate` struct tag.
+// The parsed rules are cached by the tag.
+func parseValidationRules(tag string) ([]*validationRule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	validationRulesCacheMu.RLock()
+	rules, found := validationRulesCache[tag]
+	validationRulesCacheMu.RUnlock()
+	if found {
+		return rules, nil
+	}
+	for s := tag; s != ""; {
+		var rule string
+		if strings.HasPrefix(s, "regexp=") {
+			rule, s = s, ""
+		} else if i := strings.IndexByte(s, ','); i >= 0 {
+			rule, s = s[:i], s[i+1:]
+		} else {
+			rule, s = s, ""
+		}
+		r := &validationRule{name: rule}
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			r.name, r.param = rule[:i], rule[i+1:]
+		}
+		switch r.name {
+		case "required", "email", "url":
+			if r.param != "" {
+				return nil, fmt.Errorf("rule %v doesn't take a parameter", r.name)
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of rule %v: %q", r.name, r.param)
+			}
+			r.n = n
+		case "regexp":
+			re, err := regexp.Compile(`\A(?:` + r.param + `)\z`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of rule %v: %v", r.name, err)
+			}
+			r.re = re
+		case "maxsize":
+			n, err := parseFileSize(r.param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of rule %v: %q", r.name, r.param)
+			}
+			r.n = float64(n)
+		case "mimetype":
+			if r.param == "" {
+				return nil, fmt.Errorf("invalid parameter of rule %v: %q", r.name, r.param)
+			}
+			r.mimeTypes = strings.Split(r.param, "|")
+		default:
+			return nil, fmt.Errorf("unknown validation rule %q", rule)
+		}
+		rules = append(rules, r)
+	}
+	validationRulesCacheMu.Lock()
+	validationRulesCache[tag] = rules
+	validationRulesCacheMu.Unlock()
+	return rules, nil
+}
+
+// validate validates v by the rule. It returns a *ValidationError if v
+// doesn't satisfy the rule, and returns error if the rule cannot be applied
+// to v.
+func (r *validationRule) validate(v reflect.Value, empty bool) (*ValidationError, error) {
+	switch r.name {
+	case "required":
+		if empty {
+			return r.error("required"), nil
+		}
+	case "min", "max", "len":
+		n, length, ok := r.measure(v)
+		if !ok || (r.name == "len" && !length) {
+			return nil, fmt.Errorf("rule %v cannot be applied to %v", r.name, v.Type())
+		}
+		unit := "characters"
+		if v.Kind() != reflect.String {
+			unit = "elements"
+		}
+		switch {
+		case r.name == "min" && n < r.n && length:
+			return r.error("shorter than %v %v", r.param, unit), nil
+		case r.name == "min" && n < r.n:
+			return r.error("less than %v", r.param), nil
+		case r.name == "max" && n > r.n && length:
+			return r.error("longer than %v %v", r.param, unit), nil
+		case r.name == "max" && n > r.n:
+			return r.error("greater than %v", r.param), nil
+		case r.name == "len" && n != r.n:
+			return r.error("not %v %v long", r.param, unit), nil
+		}
+	case "maxsize", "mimetype":
+		return r.validateFiles(v)
+	case "email", "url", "regexp":
+		if v.Kind() != reflect.String {
+			return nil, fmt.Errorf("rule %v cannot be applied to %v", r.name, v.Type())
+		}
+		s := v.String()
+		switch r.name {
+		case "email":
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				return r.error("not a valid email address"), nil
+			}
+		case "url":
+			if u, err := url.ParseRequestURI(s); err != nil || u.Scheme == "" || u.Host == "" {
+				return r.error("not a valid URL"), nil
+			}
+		case "regexp":
+			if !r.re.MatchString(s) {
+				return r.error("not matched with %v", r.param), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// measure returns the length of v as length is true if v is a string,
+// slice, array or map, or returns the value of v if v is a number.
+// ok is false if v is neither of them.
+func (r *validationRule) measure(v reflect.Value) (n float64, length, ok bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	}
+	return 0, false, false
+}
+
+func (r *validationRule) error(format string, a ...interface{}) *ValidationError {
+	return &ValidationError{
+		Rule:  r.name,
+		Param: r.param,
+		msg:   fmt.Sprintf(format, a...),
+	}
+}
+
 func (params *Params) reuse() {
 	if params != nil {
 		paramsPool.Put(params)
 	}
 }
+
+// ErrInvalidBody is returned from Context.Bind if the request body cannot
+// be decoded.
+var ErrInvalidBody = errors.New("invalid request body")
+
+// bodyDecoder decodes the request body, and then adds the decoded values to
+// values by the names of form values.
+type bodyDecoder func(r io.Reader, values url.Values) error
+
+// bodyDecoderFor returns the bodyDecoder for mediaType.
+// It returns nil if mediaType isn't supported.
+func bodyDecoderFor(mediaType string) bodyDecoder {
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return decodeJSONBody
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return decodeXMLBody
+	case mediaType == "application/msgpack", mediaType == "application/x-msgpack":
+		return decodeMsgpackBody
+	}
+	return nil
+}
+
+func decodeJSONBody(r io.Reader, values url.Values) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil && err != io.EOF {
+		return err
+	}
+	flattenBodyValue(values, "", v)
+	return nil
+}
+
+var msgpackBodyHandle = &codec.MsgpackHandle{RawToString: true}
+
+func decodeMsgpackBody(r io.Reader, values url.Values) error {
+	var v interface{}
+	if err := codec.NewDecoder(r, msgpackBodyHandle).Decode(&v); err != nil && err != io.EOF {
+		return err
+	}
+	flattenBodyValue(values, "", v)
+	return nil
+}
+
+// flattenBodyValue adds v to values by the names of form values.
+// Objects are flattened to "key.name", arrays of objects are flattened to
+// "key[index]", and arrays of the other values are added as multiple values
+// of key.
+func flattenBodyValue(values url.Values, key string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			flattenBodyValue(values, joinParamKey(key, name), value)
+		}
+	case map[interface{}]interface{}:
+		for name, value := range v {
+			flattenBodyValue(values, joinParamKey(key, fmt.Sprint(name)), value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			switch value.(type) {
+			case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+				flattenBodyValue(values, fmt.Sprintf("%s[%d]", key, i), value)
+			default:
+				flattenBodyValue(values, key, value)
+			}
+		}
+	case nil:
+		// null doesn't have any value.
+	case []byte:
+		values.Add(key, string(v))
+	default:
+		values.Add(key, fmt.Sprint(v))
+	}
+}
+
+// xmlNode represents an element of XML.
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*xmlNode
+}
+
+func decodeXMLBody(r io.Reader, values url.Values) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root, err := decodeXMLNode(dec, start)
+			if err != nil {
+				return err
+			}
+			flattenXMLNode(values, "", root)
+			return nil
+		}
+	}
+}
+
+func decodeXMLNode(dec *xml.Decoder, start xml.StartElement) (*xmlNode, error) {
+	node := &xmlNode{name: start.Name.Local, attrs: start.Attr}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLNode(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		case xml.CharData:
+			node.text += string(t)
+		case xml.EndElement:
+			node.text = strings.TrimSpace(node.text)
+			return node, nil
+		}
+	}
+}
+
+// flattenXMLNode adds the attributes and the children of node to values by
+// the names of form values.
+// The texts of the elements that have no attributes and no children are added
+// as the values of "key.name". The other elements are flattened to
+// "key.name", or "key.name[index]" if the elements of the same name are
+// repeated.
+func flattenXMLNode(values url.Values, key string, node *xmlNode) {
+	for _, attr := range node.attrs {
+		values.Add(joinParamKey(key, attr.Name.Local), attr.Value)
+	}
+	var names []string
+	group := map[string][]*xmlNode{}
+	for _, child := range node.children {
+		if _, exists := group[child.name]; !exists {
+			names = append(names, child.name)
+		}
+		group[child.name] = append(group[child.name], child)
+	}
+	for _, name := range names {
+		childKey := joinParamKey(key, name)
+		children := group[name]
+		for i, child := range children {
+			switch {
+			case len(child.attrs) == 0 && len(child.children) == 0:
+				values.Add(childKey, child.text)
+			case len(children) == 1:
+				flattenXMLNode(values, childKey, child)
+			default:
+				flattenXMLNode(values, fmt.Sprintf("%s[%d]", childKey, i), child)
+			}
+		}
+	}
+}
+
+// joinParamKey returns the name of form value that is name in key.
+func joinParamKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// numberFormat represents the separators of localized numbers.
+type numberFormat struct {
+	grouping string // grouping separator. Empty if no grouping.
+	decimal  string // decimal separator.
+}
+
+// parseNumberFormat parses the example of number such as "1,234.5",
+// "1.234,5", "1 234,5" and "1234.5", and returns the numberFormat of it.
+func parseNumberFormat(example string) (*numberFormat, error) {
+	var seps []string
+	var digits []int // number of digits after each separator.
+	for i := 0; i < len(example); {
+		j := i
+		for j < len(example) && (example[j] < '0' || '9' < example[j]) {
+			j++
+		}
+		if j > i {
+			seps = append(seps, example[i:j])
+			digits = append(digits, 0)
+		}
+		i = j
+		for ; i < len(example) && '0' <= example[i] && example[i] <= '9'; i++ {
+			if len(digits) > 0 {
+				digits[len(digits)-1]++
+			}
+		}
+	}
+	last := len(seps) - 1
+	if last < 0 || example[0] < '0' || '9' < example[0] || digits[last] == 0 {
+		return nil, fmt.Errorf("invalid number format: %q", example)
+	}
+	for _, sep := range seps[:last] {
+		if sep != seps[0] {
+			return nil, fmt.Errorf("invalid number format: %q", example)
+		}
+	}
+	switch {
+	case seps[last] != seps[0]:
+		return &numberFormat{grouping: seps[0], decimal: seps[last]}, nil
+	case last == 0 && digits[last] != 3:
+		return &numberFormat{decimal: seps[last]}, nil
+	}
+	return nil, fmt.Errorf("ambiguous number format: %q; it must have the decimal separator such as \"1,234.5\"", example)
+}
+
+// normalize returns the number that s is converted to the format of strconv.
+// The digits between grouping separators must be three.
+func (f *numberFormat) normalize(s string) (string, error) {
+	intPart, fracPart := s, ""
+	if i := strings.LastIndex(s, f.decimal); i >= 0 {
+		intPart, fracPart = s[:i], "."+s[i+len(f.decimal):]
+	}
+	if f.grouping != "" && strings.Contains(intPart, f.grouping) {
+		groups := strings.Split(intPart, f.grouping)
+		if first := strings.TrimLeft(groups[0], "+-"); first == "" || len(first) > 3 {
+			return "", fmt.Errorf("invalid grouping of number: %q", s)
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return "", fmt.Errorf("invalid grouping of number: %q", s)
+			}
+		}
+		intPart = strings.Join(groups, "")
+	}
+	return intPart + fracPart, nil
+}
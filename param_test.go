@@ -1,9 +1,15 @@
 package kocha_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/naoina/kocha"
 )
@@ -101,3 +107,432 @@ func TestFromParams_Bind(t *testing.T) {
 		}
 	}()
 }
+
+func bindParams(t *testing.T, values url.Values, obj interface{}, fieldNames ...string) (map[string][]string, error) {
+	return bindParamsWithConfig(t, newConfig(), values, obj, fieldNames...)
+}
+
+func bindParamsWithConfig(t *testing.T, config *kocha.Config, values url.Values, obj interface{}, fieldNames ...string) (map[string][]string, error) {
+	var errs map[string][]string
+	var bindErr error
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			bindErr = c.Params.From("user").Bind(obj, fieldNames...)
+			errs = map[string][]string{}
+			for name, es := range c.Errors {
+				for _, e := range es {
+					errs[name] = append(errs[name], e.Error())
+				}
+			}
+			return c.RenderText("")
+		}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = values
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	return errs, bindErr
+}
+
+func TestParams_Bind_withValidation(t *testing.T) {
+	type User struct {
+		Name    string  `validate:"required,min=3,max=8"`
+		Email   string  `validate:"required,email"`
+		Age     int     `validate:"min=18,max=150"`
+		Code    string  `validate:"len=4,regexp=[a-z]{2},[0-9]"`
+		Site    string  `validate:"url"`
+		Score   float64 `validate:"max=1.5"`
+		Comment string
+	}
+	for _, v := range []struct {
+		values url.Values
+		expect map[string][]string
+	}{
+		{url.Values{
+			"user.name":  {"naoina"},
+			"user.email": {"naoina@example.com"},
+			"user.age":   {"20"},
+			"user.code":  {"ab,1"},
+			"user.site":  {"http://example.com/"},
+			"user.score": {"1.5"},
+		}, map[string][]string{}},
+		{url.Values{}, map[string][]string{
+			"name":  {"name is required"},
+			"email": {"email is required"},
+		}},
+		{url.Values{
+			"user.name":  {""},
+			"user.email": {"naoina"},
+			"user.age":   {""},
+			"user.code":  {""},
+		}, map[string][]string{
+			"name":  {"name is required"},
+			"email": {"email is not a valid email address"},
+			"age":   {"age is invalid format"},
+		}},
+		{url.Values{
+			"user.name":  {"なおいな"},
+			"user.email": {"Naoina <naoina@example.com>"},
+			"user.age":   {"17"},
+			"user.code":  {"ab,12"},
+			"user.site":  {"/path/to/site"},
+			"user.score": {"1.6"},
+		}, map[string][]string{
+			"email": {"email is not a valid email address"},
+			"age":   {"age is less than 18"},
+			"code":  {"code is not 4 characters long", "code is not matched with [a-z]{2},[0-9]"},
+			"site":  {"site is not a valid URL"},
+			"score": {"score is greater than 1.5"},
+		}},
+		{url.Values{
+			"user.name":  {"na"},
+			"user.email": {"naoina@example.com"},
+			"user.age":   {"151"},
+		}, map[string][]string{
+			"name": {"name is shorter than 3 characters"},
+			"age":  {"age is greater than 150"},
+		}},
+		{url.Values{
+			"user.name":  {"naoina-kocha"},
+			"user.email": {"naoina@example.com"},
+		}, map[string][]string{
+			"name": {"name is longer than 8 characters"},
+		}},
+	} {
+		user := &User{}
+		actual, err := bindParams(t, v.values, user, "name", "email", "age", "code", "site", "score", "comment")
+		if err != nil {
+			t.Errorf("Bind(%#v) with %#v => %#v; want nil", user, v.values, err)
+		}
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v; Errors => %#v; want %#v", user, v.values, actual, expect)
+		}
+	}
+}
+
+func TestParams_Bind_withInvalidValidationTag(t *testing.T) {
+	for _, v := range []struct {
+		obj    interface{}
+		expect string
+	}{
+		{&struct {
+			Name string `validate:"required,unknown"`
+		}{}, `kocha: Bind: .Name: unknown validation rule "unknown"`},
+		{&struct {
+			Name string `validate:"min=a"`
+		}{}, `kocha: Bind: .Name: invalid parameter of rule min: "a"`},
+		{&struct {
+			Name string `validate:"required=1"`
+		}{}, `kocha: Bind: .Name: rule required doesn't take a parameter`},
+		{&struct {
+			Name int `validate:"len=3"`
+		}{}, `kocha: Bind: .Name: rule len cannot be applied to int`},
+		{&struct {
+			Name int `validate:"email"`
+		}{}, `kocha: Bind: .Name: rule email cannot be applied to int`},
+	} {
+		_, err := bindParams(t, url.Values{"user.name": {"1"}}, v.obj, "name")
+		actual := fmt.Sprint(err)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) => %#v; want %#v", v.obj, actual, expect)
+		}
+	}
+}
+
+func TestParams_Bind_withCompositeTypes(t *testing.T) {
+	type Item struct {
+		Name  string `validate:"required"`
+		Count int
+	}
+	type Address struct {
+		City    string
+		ZipCode string `validate:"len=7"`
+	}
+	type User struct {
+		Tags     []string
+		Scores   []int
+		Items    []Item
+		Meta     map[string]string
+		Stock    map[string]*Item
+		Address  Address
+		Previous *Address
+		Age      *int
+		Nickname *string
+	}
+	age := 17
+	for _, v := range []struct {
+		values     url.Values
+		expect     *User
+		expectErrs map[string][]string
+	}{
+		{url.Values{}, &User{}, map[string][]string{}},
+		{url.Values{
+			"user.tags":             {"a", "b"},
+			"user.scores":           {"1", "2", "3"},
+			"user.items[1].name":    {"book"},
+			"user.items[1].count":   {"3"},
+			"user.items[0].name":    {"pen"},
+			"user.items[10].name":   {"note"},
+			"user.meta[color]":      {"red"},
+			"user.meta[size]":       {"L"},
+			"user.stock[pen].name":  {"pen"},
+			"user.stock[pen].count": {"5"},
+			"user.address.city":     {"Tokyo"},
+			"user.address.zip_code": {"1000001"},
+			"user.previous.city":    {"Osaka"},
+			"user.age":              {"17"},
+			"admin.tags":            {"c"},
+		}, &User{
+			Tags:     []string{"a", "b"},
+			Scores:   []int{1, 2, 3},
+			Items:    []Item{{Name: "pen"}, {Name: "book", Count: 3}, {Name: "note"}},
+			Meta:     map[string]string{"color": "red", "size": "L"},
+			Stock:    map[string]*Item{"pen": {Name: "pen", Count: 5}},
+			Address:  Address{City: "Tokyo", ZipCode: "1000001"},
+			Previous: &Address{City: "Osaka"},
+			Age:      &age,
+		}, map[string][]string{}},
+		{url.Values{
+			"user.scores":           {"1", "a"},
+			"user.items[0].count":   {"1"},
+			"user.items[x].name":    {"pen"},
+			"user.stock[pen].count": {"a"},
+			"user.address.zip_code": {"100"},
+		}, &User{
+			Stock:   map[string]*Item{},
+			Address: Address{ZipCode: "100"},
+		}, map[string][]string{
+			"scores":           {"scores is invalid format"},
+			"items":            {"items is invalid format"},
+			"stock[pen].count": {"stock[pen].count is invalid format"},
+			"stock[pen].name":  {"stock[pen].name is required"},
+			"address.zip_code": {"address.zip_code is not 7 characters long"},
+		}},
+		{url.Values{
+			"user.items[0].count": {"1"},
+		}, &User{
+			Items: []Item{{Count: 1}},
+		}, map[string][]string{
+			"items[0].name": {"items[0].name is required"},
+		}},
+	} {
+		user := &User{}
+		errs, err := bindParams(t, v.values, user, "tags", "scores", "items", "meta", "stock", "address", "previous", "age", "nickname")
+		if err != nil {
+			t.Errorf("Bind(%#v) with %#v => %#v; want nil", user, v.values, err)
+		}
+		var actual interface{} = user
+		var expect interface{} = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v => %#v; want %#v", user, v.values, actual, expect)
+		}
+		actual = errs
+		expect = v.expectErrs
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v; Errors => %#v; want %#v", user, v.values, actual, expect)
+		}
+	}
+}
+
+type testStatus int
+
+const (
+	testStatusActive testStatus = iota + 1
+	testStatusInactive
+)
+
+type testMoney struct {
+	Amount   int64
+	Currency string
+}
+
+func TestParams_Bind_withParamBinders(t *testing.T) {
+	kocha.ParamBinders.Set(reflect.TypeOf(testStatus(0)), func(s string) (interface{}, error) {
+		switch s {
+		case "active":
+			return testStatusActive, nil
+		case "inactive":
+			return testStatusInactive, nil
+		}
+		return nil, fmt.Errorf("unknown status")
+	})
+	defer kocha.ParamBinders.Del(reflect.TypeOf(testStatus(0)))
+	kocha.ParamBinders.Set(reflect.TypeOf(testMoney{}), func(s string) (interface{}, error) {
+		var m testMoney
+		if _, err := fmt.Sscanf(s, "%d %s", &m.Amount, &m.Currency); err != nil {
+			return nil, kocha.ErrInvalidFormat
+		}
+		return m, nil
+	})
+	defer kocha.ParamBinders.Del(reflect.TypeOf(testMoney{}))
+	type User struct {
+		Status   testStatus
+		Statuses []testStatus
+		Balance  *testMoney
+		IP       net.IP
+		Born     time.Time
+	}
+	for _, v := range []struct {
+		values     url.Values
+		expect     *User
+		expectErrs map[string][]string
+	}{
+		{url.Values{
+			"user.status":   {"inactive"},
+			"user.statuses": {"active", "inactive"},
+			"user.balance":  {"100 JPY"},
+			"user.ip":       {"192.0.2.1"},
+			"user.born":     {"2000-01-02"},
+		}, &User{
+			Status:   testStatusInactive,
+			Statuses: []testStatus{testStatusActive, testStatusInactive},
+			Balance:  &testMoney{Amount: 100, Currency: "JPY"},
+			IP:       net.ParseIP("192.0.2.1"),
+			Born:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		}, map[string][]string{}},
+		{url.Values{
+			"user.status":  {"deleted"},
+			"user.balance": {"JPY"},
+			"user.ip":      {"192.0.2"},
+		}, &User{
+			Balance: &testMoney{},
+		}, map[string][]string{
+			"status":  {"status is unknown status"},
+			"balance": {"balance is invalid format"},
+			"ip":      {"ip is invalid format"},
+		}},
+	} {
+		user := &User{}
+		errs, err := bindParams(t, v.values, user, "status", "statuses", "balance", "ip", "born")
+		if err != nil {
+			t.Errorf("Bind(%#v) with %#v => %#v; want nil", user, v.values, err)
+		}
+		var actual interface{} = user
+		var expect interface{} = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v => %#v; want %#v", user, v.values, actual, expect)
+		}
+		actual = errs
+		expect = v.expectErrs
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v; Errors => %#v; want %#v", user, v.values, actual, expect)
+		}
+	}
+
+	kocha.ParamBinders.Set(reflect.TypeOf(testStatus(0)), func(s string) (interface{}, error) {
+		return s, nil
+	})
+	_, err := bindParams(t, url.Values{"user.status": {"active"}}, &User{}, "status")
+	actual := fmt.Sprint(err)
+	expect := "kocha: Bind: binder for kocha_test.testStatus returns string"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Bind with invalid binder => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestParams_Bind_withTimeAndNumberFormats(t *testing.T) {
+	type Order struct {
+		Date      time.Time `time:"02/01/2006|02/01/2006 15:04"`
+		Shipped   time.Time
+		Price     float64 `number:"1.234,5"`
+		Quantity  int     `number:"1 234,5"`
+		Discount  float64
+		Customers []uint `number:"1,234.5"`
+	}
+	for _, v := range []struct {
+		values     url.Values
+		expect     *Order
+		expectErrs map[string][]string
+	}{
+		{url.Values{
+			"user.date":      {"31/12/2016 10:20"},
+			"user.shipped":   {"2017-01-02"},
+			"user.price":     {"1.234.567,89"},
+			"user.quantity":  {"12 345"},
+			"user.discount":  {"1234.5"},
+			"user.customers": {"1,234", "56"},
+		}, &Order{
+			Date:      time.Date(2016, 12, 31, 10, 20, 0, 0, time.UTC),
+			Shipped:   time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC),
+			Price:     1234567.89,
+			Quantity:  12345,
+			Discount:  1234.5,
+			Customers: []uint{1234, 56},
+		}, map[string][]string{}},
+		{url.Values{
+			"user.date":      {"2016-12-31"},
+			"user.price":     {"1.5"},
+			"user.quantity":  {"1,5"},
+			"user.discount":  {"1,234.5"},
+			"user.customers": {"12,34"},
+		}, &Order{}, map[string][]string{
+			"date":      {"date is invalid format"},
+			"price":     {"price is invalid format"},
+			"quantity":  {"quantity is invalid format"},
+			"discount":  {"discount is invalid format"},
+			"customers": {"customers is invalid format"},
+		}},
+	} {
+		order := &Order{}
+		actual, err := bindParams(t, v.values, order, "date", "shipped", "price", "quantity", "discount", "customers")
+		if err != nil {
+			t.Errorf("Bind(%#v) with %#v => %#v; want nil", order, v.values, err)
+		}
+		var actualOrder interface{} = order
+		var expectOrder interface{} = v.expect
+		if !reflect.DeepEqual(actualOrder, expectOrder) {
+			t.Errorf("Bind(%#v) with %#v => %#v; want %#v", order, v.values, actualOrder, expectOrder)
+		}
+		expect := v.expectErrs
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) with %#v; Errors => %#v; want %#v", order, v.values, actual, expect)
+		}
+	}
+}
+
+func TestParams_Bind_withConfigFormats(t *testing.T) {
+	type Order struct {
+		Date  time.Time
+		Price float64
+		Count int `number:"1,234.5"`
+	}
+	config := newConfig()
+	config.TimeFormats = []string{"02.01.2006"}
+	config.NumberFormat = "1.234,5"
+	order := &Order{}
+	errs, err := bindParamsWithConfig(t, config, url.Values{
+		"user.date":  {"31.12.2016"},
+		"user.price": {"1.234,5"},
+		"user.count": {"1,234"},
+	}, order, "date", "price", "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{order, errs}
+	var expect interface{} = []interface{}{&Order{
+		Date:  time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC),
+		Price: 1234.5,
+		Count: 1234,
+	}, map[string][]string{}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Bind(%#v) => %#v; want %#v", order, actual, expect)
+	}
+
+	for _, format := range []string{"1234", "1,234", "-1,234.5", "1,234.", "1,234.567,8"} {
+		config := newConfig()
+		config.NumberFormat = format
+		if _, err := kocha.New(config); err == nil {
+			t.Errorf("kocha.New with NumberFormat %q => (_, nil); want (_, error)", format)
+		}
+	}
+}
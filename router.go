@@ -1,18 +1,96 @@
 package kocha
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"net/url"
 	"reflect"
-
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/naoina/denco"
 	"github.com/naoina/kocha/util"
 )
 
+// httpMethods is the HTTP methods that can be handled by Controller in order
+// of the Allow header.
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+var defaultControllerType = reflect.TypeOf(DefaultController{})
+
+// promotedMethodPos is the position of the method that is promoted from the
+// embedded *DefaultController, keyed by the method name.
+// It's used to tell the methods that are declared by the controller from the
+// methods that are promoted from the embedded field.
+var promotedMethodPos = func() map[string]string {
+	t := reflect.TypeOf(&struct{ *DefaultController }{})
+	pos := make(map[string]string, len(httpMethods))
+	for _, method := range httpMethods {
+		m, _ := t.MethodByName(method)
+		pos[method] = methodPos(m)
+	}
+	return pos
+}()
+
 // The routing table.
 type RouteTable []*Route
 
+// Append returns a new RouteTable that appends routes to rt.
+func (rt RouteTable) Append(routes RouteTable) RouteTable {
+	return append(append(RouteTable{}, rt...), routes...)
+}
+
+// RouteInfo represents the information of a route.
+type RouteInfo struct {
+	Name       string   `json:"name"`
+	Methods    []string `json:"methods"`
+	Host       string   `json:"host,omitempty"`
+	Path       string   `json:"path"`
+	ParamNames []string `json:"param_names"` // names of the parameters of Host and Path in order of arguments of Router.Reverse.
+	Handler    string   `json:"handler"`     // type name of Controller or function name of Handler.
+}
+
+// Inspect returns the information of the routes in rt.
+// It also returns the warnings about the duplicated names and the routes that
+// will never be dispatched. Unlike kocha.New, Inspect doesn't fail on them so
+// that they can be reported all at once.
+func (rt RouteTable) Inspect() (infos []RouteInfo, warnings []string, err error) {
+	names := make(map[string]bool)
+	for i, route := range rt {
+		if err := route.build(); err != nil {
+			return nil, nil, err
+		}
+		if route.Name != "" {
+			if names[route.Name] {
+				warnings = append(warnings, fmt.Sprintf("route name %v is duplicated", route.Name))
+			}
+			names[route.Name] = true
+		}
+		if len(route.methods) == 0 {
+			warnings = append(warnings, fmt.Sprintf("route %v is unreachable because it handles no methods", route.Name))
+		}
+		for _, r := range rt[:i] {
+			if len(route.methods) > 0 && r.shadows(route) {
+				warnings = append(warnings, fmt.Sprintf("route %v is unreachable because route %v precedes it", route.Name, r.Name))
+				break
+			}
+		}
+		info := RouteInfo{
+			Name:       route.Name,
+			Methods:    route.methods,
+			Host:       route.Host,
+			Path:       route.Path,
+			ParamNames: append(append([]string{}, route.hostParamNames...), route.paramNames...),
+			Handler:    route.handlerName(),
+		}
+		infos = append(infos, info)
+	}
+	return infos, warnings, nil
+}
+
 func (rt RouteTable) buildRouter() (*Router, error) {
 	router := &Router{routeTable: rt}
 	if err := router.buildForward(); err != nil {
@@ -29,46 +107,125 @@ type Router struct {
 	forward    *denco.Router
 	reverse    map[string]*Route
 	routeTable RouteTable
+
+	// prefix is the path prefix of the URLs that are returned by Reverse.
+	// It is set when the application is mounted by Mount.
+	prefix string
+
+	// mounted reports whether the application has been mounted by Mount.
+	mounted bool
 }
 
-func (router *Router) dispatch(req *Request) (name string, handler requestHandler, params denco.Params, found bool) {
+// dispatch returns the route and the handler for req.
+// If the route of the path of req is found but it doesn't handle the method
+// of req, dispatch returns found as false and allowed as the methods that are
+// allowed for the path. route and allowed are nil if the route is not found.
+func (router *Router) dispatch(req *Request) (route *Route, handler requestHandler, params denco.Params, allowed []string, found bool) {
+	matched, matchedParams := router.match(req, func(route *Route) bool {
+		handler, found = route.dispatch(req.Method)
+		return found
+	})
+	if len(matched) == 0 {
+		return nil, nil, nil, nil, false
+	}
+	if found {
+		return matched[0], handler, matchedParams, nil, true
+	}
+	return matched[0], nil, matchedParams, allowedMethods(matched), false
+}
+
+// match returns the routes that match the path and the host of req, and the
+// parameters of the first route of them.
+// If stop returns true for a route, match returns only the route.
+func (router *Router) match(req *Request, stop func(route *Route) bool) (matched []*Route, matchedParams denco.Params) {
 	path := util.NormPath(req.URL.Path)
 	data, params, found := router.forward.Lookup(path)
 	if !found {
-		return "", nil, nil, false
+		return nil, nil
+	}
+	for _, route := range data.([]*Route) {
+		ps, ok := route.matchParams(params)
+		if !ok {
+			continue
+		}
+		hostParams, ok := route.matchHost(req.Host)
+		if !ok {
+			continue
+		}
+		ps = append(ps, hostParams...)
+		if stop != nil && stop(route) {
+			return []*Route{route}, ps
+		}
+		if matched == nil {
+			matchedParams = ps
+		}
+		matched = append(matched, route)
 	}
-	route := data.(*Route)
-	handler, found = route.dispatch(req.Method)
-	return route.Name, handler, params, found
+	return matched, matchedParams
 }
 
 // buildForward builds forward router.
+// Routes that have the same path except names and constraints of the
+// parameters are grouped into one record, and they are tried in order of the
+// routing table when dispatching.
 func (router *Router) buildForward() error {
-	records := make([]denco.Record, len(router.routeTable))
+	var records []denco.Record
+	index := make(map[string]int)
+	for _, route := range router.routeTable {
+		if err := route.build(); err != nil {
+			return err
+		}
+		key := route.pathShape()
+		if i, exists := index[key]; exists {
+			records[i].Value = append(records[i].Value.([]*Route), route)
+			continue
+		}
+		index[key] = len(records)
+		records = append(records, denco.NewRecord(route.path, []*Route{route}))
+	}
 	for i, route := range router.routeTable {
-		records[i] = denco.NewRecord(route.Path, route)
+		if len(route.methods) == 0 {
+			continue
+		}
+		for _, r := range router.routeTable[:i] {
+			if r.shadows(route) {
+				return fmt.Errorf("kocha: route %v is unreachable because route %v precedes it: %v and %v", route.Name, r.Name, r.Path, route.Path)
+			}
+		}
 	}
 	router.forward = denco.New()
 	return router.forward.Build(records)
 }
 
 // buildReverse builds reverse router.
+// It returns an error if the name of the route is duplicated.
 func (router *Router) buildReverse() error {
 	router.reverse = make(map[string]*Route)
 	for _, route := range router.routeTable {
-		router.reverse[route.Name] = route
-		for i := 0; i < len(route.Path); i++ {
-			if c := route.Path[i]; c == denco.ParamCharacter || c == denco.WildcardCharacter {
-				next := denco.NextSeparator(route.Path, i+1)
-				route.paramNames = append(route.paramNames, route.Path[i:next])
-				i = next
-			}
+		if route.Name == "" {
+			continue
 		}
+		if r, exists := router.reverse[route.Name]; exists {
+			return fmt.Errorf("kocha: route name %v is duplicated: %v and %v", route.Name, r.Path, route.Path)
+		}
+		router.reverse[route.Name] = route
 	}
 	return nil
 }
 
+// Fragment represents a fragment identifier of URL for Router.Reverse.
+type Fragment string
+
 // Reverse returns path of route by name and any params.
+//
+// The values of the path parameters are percent-encoded. If the route has
+// Host, Reverse returns an absolute URL, and the values of the parameters of
+// Host must be given before the values of the path parameters. The last arguments
+// can be a query and a fragment in this order. The query is one of url.Values,
+// map[string]string and map[string]interface{}, and the fragment is Fragment.
+//
+//	router.Reverse("search", url.Values{"q": {"go"}}, kocha.Fragment("top"))
+//	// => "/search?q=go#top"
 func (router *Router) Reverse(name string, v ...interface{}) (string, error) {
 	route := router.reverse[name]
 	if route == nil {
@@ -78,46 +235,430 @@ func (router *Router) Reverse(name string, v ...interface{}) (string, error) {
 		}
 		return "", fmt.Errorf("kocha: no match route found: %v (%v)", name, strings.Join(types, ", "))
 	}
-	return route.reverse(v...)
+	return route.reverse(router.prefix, v...)
+}
+
+// RouteGroup represents a group of routes that share the path prefix and the
+// middlewares.
+//
+//	kocha.RouteTable{
+//	    {Name: "root", Path: "/", Controller: &controller.Root{}},
+//	}.Append((&kocha.RouteGroup{
+//	    Prefix:      "/admin",
+//	    Middlewares: []kocha.Middleware{&AdminAuthMiddleware{}},
+//	    Routes: kocha.RouteTable{
+//	        {Name: "admin_users", Path: "/users", Controller: &admin.Users{}},
+//	    },
+//	}).RouteTable())
+type RouteGroup struct {
+	// Prefix is the path prefix of the routes in the group.
+	Prefix string
+
+	// Middlewares is the middlewares that are processed for the routes in the
+	// group. They are processed before Route.Middlewares of each route.
+	Middlewares []Middleware
+
+	// Host and Scheme are set to the routes in the group that don't have
+	// them. See Route.Host and Route.Scheme.
+	Host   string
+	Scheme string
+
+	// Routes is the routes in the group.
+	// The routes of the nested groups can be included by RouteGroup.RouteTable.
+	Routes RouteTable
+}
+
+// RouteTable returns a new RouteTable that contains copies of the routes in
+// the group. Path of each route is prefixed with Prefix, and Middlewares of
+// the group are prepended to Middlewares of each route.
+func (g *RouteGroup) RouteTable() RouteTable {
+	rt := make(RouteTable, len(g.Routes))
+	for i, route := range g.Routes {
+		r := *route
+		r.Path = joinPath(g.Prefix, route.Path)
+		r.Middlewares = append(append([]Middleware{}, g.Middlewares...), route.Middlewares...)
+		if r.Host == "" {
+			r.Host = g.Host
+		}
+		if r.Scheme == "" {
+			r.Scheme = g.Scheme
+		}
+		r.path = ""
+		r.paramNames = nil
+		r.constraints = nil
+		r.hostLabels = nil
+		r.hostParamNames = nil
+		r.hostConstraints = nil
+		r.methods = nil
+		rt[i] = &r
+	}
+	return rt
+}
+
+// joinPath returns the path of p that is prefixed with prefix.
+func joinPath(prefix, p string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return p
+	}
+	if p == "" || p == "/" {
+		return prefix
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	return prefix + p
 }
 
 // Route represents a route.
+//
+// Each parameter in Path can have a constraint in the form of
+// ":name<constraint>" or "*name<constraint>". The constraint is a regular
+// expression that must match the whole value of the parameter, or one of the
+// following names:
+//
+//	int   an integer (e.g. "-1", "10")
+//	uint  an unsigned integer (e.g. "10")
+//	uuid  a UUID (e.g. "0f8fad5b-d9cb-469f-a165-70867728950e")
+//
+// The constraint cannot contain "/". If a request violates the constraint,
+// the route is skipped and the following routes are tried.
+//
+// A route is handled by either Controller or Handler.
+// If Method is empty, the request will be dispatched to the method of
+// Controller that corresponds to the HTTP method of the request.
+// Otherwise, only the request of Method will be dispatched to Handler. By
+// using Method and Handler, two or more routes can share the same path with
+// the different methods.
 type Route struct {
 	Name       string
 	Path       string
 	Controller Controller
 
-	paramNames []string
+	// Method is an HTTP method of the request that is handled by Handler.
+	Method string
+
+	// Handler is a handler for the request of Method.
+	Handler func(c *Context) error
+
+	// Middlewares is the middlewares that are processed only for the
+	// requests that are dispatched to this route. They are processed after
+	// Config.Middlewares.
+	Middlewares []Middleware
+
+	// Host is a host name that the route matches to. It must not contain
+	// a port. If Host is empty, the route matches to any host.
+	// Each label of Host can be a parameter in the form of ":name" or
+	// ":name<constraint>" (e.g. ":tenant.example.com"). The values of the
+	// parameters are added to Context.Params as well as the path parameters.
+	Host string
+
+	// Scheme is a scheme of the absolute URL that is returned by
+	// Router.Reverse for the route that has Host. Default is "http".
+	Scheme string
+
+	path            string // Path without the constraints.
+	paramNames      []string
+	constraints     []*paramConstraint
+	hostLabels      []string // lower-cased labels of Host. Empty for parameters.
+	hostParamNames  []string
+	hostConstraints []*paramConstraint
+	methods         []string
+	anyMethod       bool // whether Controller is anyMethodController.
+}
+
+func (route *Route) validate() error {
+	switch {
+	case route.Method == "" && route.Handler != nil:
+		return fmt.Errorf("kocha: route %v: Method must be specified with Handler", route.Name)
+	case route.Method != "" && route.Handler == nil:
+		return fmt.Errorf("kocha: route %v: Handler must be specified with Method", route.Name)
+	case route.Handler != nil && route.Controller != nil:
+		return fmt.Errorf("kocha: route %v: Controller and Handler cannot be specified together", route.Name)
+	case route.Handler == nil && route.Controller == nil:
+		return fmt.Errorf("kocha: route %v: Controller or Handler must be specified", route.Name)
+	case route.Scheme != "" && route.Host == "":
+		return fmt.Errorf("kocha: route %v: Host must be specified with Scheme", route.Name)
+	}
+	return nil
+}
+
+// build validates the route, and then builds the internal information of it.
+func (route *Route) build() error {
+	if err := route.validate(); err != nil {
+		return err
+	}
+	if err := route.buildPath(); err != nil {
+		return err
+	}
+	if err := route.buildHost(); err != nil {
+		return err
+	}
+	if h, ok := route.Controller.(*HTTPHandler); ok && h.Param != "" && !route.hasParam("*"+h.Param) {
+		return fmt.Errorf("kocha: route %v: HTTPHandler.Param %q is not a wildcard parameter of %v", route.Name, h.Param, route.Path)
+	}
+	route.buildMethods()
+	return nil
+}
+
+// hasParam returns whether the path of the route has the parameter of name.
+// name must be prefixed with ':' or '*'.
+func (route *Route) hasParam(name string) bool {
+	for _, n := range route.paramNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPath parses Path, and then builds the path without the constraints,
+// the names of the parameters and the constraints of them.
+func (route *Route) buildPath() error {
+	var buf bytes.Buffer
+	route.paramNames, route.constraints = nil, nil
+	for i := 0; i < len(route.Path); i++ {
+		c := route.Path[i]
+		buf.WriteByte(c)
+		if c != denco.ParamCharacter && c != denco.WildcardCharacter {
+			continue
+		}
+		next := denco.NextSeparator(route.Path, i+1)
+		name, expr := route.Path[i+1:next], ""
+		if j := strings.IndexByte(name, '<'); j >= 0 {
+			if name[len(name)-1] != '>' {
+				return fmt.Errorf("kocha: route %v: invalid constraint: %v", route.Name, route.Path[i:next])
+			}
+			name, expr = name[:j], name[j+1:len(name)-1]
+		}
+		constraint, err := newParamConstraint(expr)
+		if err != nil {
+			return fmt.Errorf("kocha: route %v: invalid constraint: %v: %v", route.Name, route.Path[i:next], err)
+		}
+		buf.WriteString(name)
+		route.paramNames = append(route.paramNames, string(c)+name)
+		route.constraints = append(route.constraints, constraint)
+		i = next - 1
+	}
+	route.path = buf.String()
+	return nil
+}
+
+// buildHost parses Host, and then builds the labels of the host, the names of
+// the parameters and the constraints of them.
+func (route *Route) buildHost() error {
+	route.hostLabels, route.hostParamNames, route.hostConstraints = nil, nil, nil
+	if route.Host == "" {
+		return nil
+	}
+	for _, label := range strings.Split(route.Host, ".") {
+		if label == "" || label[0] != denco.ParamCharacter {
+			route.hostLabels = append(route.hostLabels, strings.ToLower(label))
+			continue
+		}
+		name, expr := label[1:], ""
+		if j := strings.IndexByte(name, '<'); j >= 0 {
+			if name[len(name)-1] != '>' {
+				return fmt.Errorf("kocha: route %v: invalid constraint: %v", route.Name, label)
+			}
+			name, expr = name[:j], name[j+1:len(name)-1]
+		}
+		constraint, err := newParamConstraint(expr)
+		if err != nil {
+			return fmt.Errorf("kocha: route %v: invalid constraint: %v: %v", route.Name, label, err)
+		}
+		for _, n := range route.paramNames {
+			if n[1:] == name {
+				return fmt.Errorf("kocha: route %v: parameter %v is duplicated in Host and Path", route.Name, name)
+			}
+		}
+		route.hostLabels = append(route.hostLabels, "")
+		route.hostParamNames = append(route.hostParamNames, string(denco.ParamCharacter)+name)
+		route.hostConstraints = append(route.hostConstraints, constraint)
+	}
+	return nil
+}
+
+// matchHost returns the params of Host, and whether host matches Host.
+// host can contain a port. If Host is empty, matchHost always returns true.
+func (route *Route) matchHost(host string) (denco.Params, bool) {
+	if route.Host == "" {
+		return nil, true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	if len(labels) != len(route.hostLabels) {
+		return nil, false
+	}
+	var params denco.Params
+	for i, label := range labels {
+		if route.hostLabels[i] != "" {
+			if label != route.hostLabels[i] {
+				return nil, false
+			}
+			continue
+		}
+		n := len(params)
+		if label == "" {
+			return nil, false
+		}
+		if c := route.hostConstraints[n]; c != nil && !c.re.MatchString(label) {
+			return nil, false
+		}
+		params = append(params, denco.Param{Name: route.hostParamNames[n][1:], Value: label})
+	}
+	return params, true
+}
+
+// pathShape returns the path without the names of the parameters.
+// Routes that have the same shape of the path are matched to the same
+// requests.
+func (route *Route) pathShape() string {
+	shape := route.path
+	for _, name := range route.paramNames {
+		shape = strings.Replace(shape, name, name[:1], 1)
+	}
+	return shape
+}
+
+// matchParams returns the params that are renamed to the names of the route,
+// and whether the values satisfy the constraints of the route.
+// params must be ordered as the parameters in the path.
+func (route *Route) matchParams(params denco.Params) (denco.Params, bool) {
+	if len(params) != len(route.paramNames) {
+		return params, false
+	}
+	ps := make(denco.Params, len(params))
+	for i, param := range params {
+		if c := route.constraints[i]; c != nil && !c.re.MatchString(param.Value) {
+			return nil, false
+		}
+		ps[i] = denco.Param{Name: route.paramNames[i][1:], Value: param.Value}
+	}
+	return ps, true
+}
+
+// buildMethods builds the methods that are handled by the route.
+func (route *Route) buildMethods() {
+	route.methods, route.anyMethod = nil, false
+	if _, ok := route.Controller.(anyMethodController); ok {
+		route.methods, route.anyMethod = append(append([]string{}, httpMethods...), "OPTIONS"), true
+		return
+	}
+	if route.Handler != nil {
+		route.methods = []string{strings.ToUpper(route.Method)}
+		return
+	}
+	var allowed map[string]bool
+	if a, ok := route.Controller.(MethodAllower); ok {
+		allowed = make(map[string]bool)
+		for _, method := range a.AllowedMethods() {
+			allowed[strings.ToUpper(method)] = true
+		}
+	}
+	for _, method := range httpMethods {
+		if route.controllerHandler(method) == nil || (allowed != nil && !allowed[method]) {
+			continue
+		}
+		if isDefaultControllerMethod(reflect.TypeOf(route.Controller), method) {
+			continue
+		}
+		route.methods = append(route.methods, method)
+	}
+}
+
+// isDefaultControllerMethod reports whether the method of t is promoted from
+// the DefaultController that is embedded in t, or t is DefaultController.
+func isDefaultControllerMethod(t reflect.Type, method string) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == defaultControllerType {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	// the method that has a value receiver is in the method set of t.
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if m, ok := typ.MethodByName(method); ok && methodPos(m) != promotedMethodPos[method] {
+			return false
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() != reflect.Ptr {
+			ft = reflect.PtrTo(ft)
+		}
+		if _, ok := ft.MethodByName(method); ok {
+			return isDefaultControllerMethod(ft, method)
+		}
+	}
+	return false
+}
+
+// methodPos returns the position of the function of m in the source.
+func methodPos(m reflect.Method) string {
+	pc := m.Func.Pointer()
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return ""
+	}
+	file, line := f.FileLine(f.Entry())
+	return fmt.Sprintf("%s:%d", file, line)
 }
 
 func (route *Route) dispatch(method string) (handler requestHandler, found bool) {
-	switch strings.ToUpper(method) {
+	if route.anyMethod {
+		return route.Controller.(anyMethodController).serve, true
+	}
+	method = strings.ToUpper(method)
+	for _, m := range route.methods {
+		if m != method {
+			continue
+		}
+		if route.Handler != nil {
+			return route.Handler, true
+		}
+		return route.controllerHandler(method), true
+	}
+	return nil, false
+}
+
+func (route *Route) controllerHandler(method string) requestHandler {
+	switch method {
 	case "GET":
 		if h, ok := route.Controller.(Getter); ok {
-			return h.GET, true
+			return h.GET
 		}
 	case "POST":
 		if h, ok := route.Controller.(Poster); ok {
-			return h.POST, true
+			return h.POST
 		}
 	case "PUT":
 		if h, ok := route.Controller.(Putter); ok {
-			return h.PUT, true
+			return h.PUT
 		}
 	case "DELETE":
 		if h, ok := route.Controller.(Deleter); ok {
-			return h.DELETE, true
+			return h.DELETE
 		}
 	case "HEAD":
 		if h, ok := route.Controller.(Header); ok {
-			return h.HEAD, true
+			return h.HEAD
 		}
 	case "PATCH":
 		if h, ok := route.Controller.(Patcher); ok {
-			return h.PATCH, true
+			return h.PATCH
 		}
 	}
-	return nil, false
+	return nil
 }
 
 // ParamNames returns names of the path parameters.
@@ -125,20 +666,279 @@ func (route *Route) ParamNames() []string {
 	return route.paramNames
 }
 
-func (r *Route) reverse(v ...interface{}) (string, error) {
+// reverse returns the URL of the route that is prefixed with prefix.
+func (r *Route) reverse(prefix string, v ...interface{}) (string, error) {
+	v, query, fragment := splitReverseArgs(v)
+	if len(v) < len(r.hostParamNames) {
+		return "", fmt.Errorf("kocha: too few arguments: %v (%v)", r.Name, r.describe())
+	}
+	n := len(r.hostParamNames)
+	path, err := r.reversePath(v[n:])
+	if err != nil {
+		return "", err
+	}
+	path = joinPath(prefix, path)
+	if r.Host != "" {
+		host, err := r.reverseHost(v[:n])
+		if err != nil {
+			return "", err
+		}
+		scheme := r.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		path = scheme + "://" + host + path
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	if fragment != "" {
+		path += (&url.URL{Fragment: string(fragment)}).String()
+	}
+	return path, nil
+}
+
+func (r *Route) reverseHost(v []interface{}) (string, error) {
+	labels := make([]string, len(r.hostLabels))
+	n := 0
+	for i, label := range r.hostLabels {
+		if label != "" {
+			labels[i] = label
+			continue
+		}
+		value := fmt.Sprint(v[n])
+		if value == "" || strings.ContainsAny(value, "./:?#@[] ") {
+			return "", fmt.Errorf("kocha: invalid argument %q for %v: %v", value, r.hostParamNames[n], r.Name)
+		}
+		if c := r.hostConstraints[n]; c != nil && !c.re.MatchString(value) {
+			return "", fmt.Errorf("kocha: invalid argument %q for %v<%v>: %v", value, r.hostParamNames[n], c.expr, r.Name)
+		}
+		labels[i] = value
+		n++
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func (r *Route) reversePath(v []interface{}) (string, error) {
 	switch vlen, nlen := len(v), len(r.paramNames); {
 	case vlen < nlen:
-		return "", fmt.Errorf("kocha: too few arguments: %v (controller is %T)", r.Name, r.Controller)
+		return "", fmt.Errorf("kocha: too few arguments: %v (%v)", r.Name, r.describe())
 	case vlen > nlen:
-		return "", fmt.Errorf("kocha: too many arguments: %v (controller is %T)", r.Name, r.Controller)
+		return "", fmt.Errorf("kocha: too many arguments: %v (%v)", r.Name, r.describe())
 	case vlen+nlen == 0:
-		return r.Path, nil
+		return r.path, nil
 	}
 	var oldnew []string
 	for i := 0; i < len(v); i++ {
-		oldnew = append(oldnew, r.paramNames[i], fmt.Sprint(v[i]))
+		value := fmt.Sprint(v[i])
+		if c := r.constraints[i]; c != nil && !c.re.MatchString(value) {
+			return "", fmt.Errorf("kocha: invalid argument %q for %v<%v>: %v", value, r.paramNames[i], c.expr, r.Name)
+		}
+		oldnew = append(oldnew, r.paramNames[i], escapePathParam(r.paramNames[i][0], value))
 	}
 	replacer := strings.NewReplacer(oldnew...)
-	path := replacer.Replace(r.Path)
+	path := replacer.Replace(r.path)
 	return util.NormPath(path), nil
 }
+
+// describe returns a description of the handler of the route for error
+// messages.
+func (r *Route) describe() string {
+	if r.Handler != nil {
+		return fmt.Sprintf("handler is %v %v", r.Method, r.Path)
+	}
+	return fmt.Sprintf("controller is %T", r.Controller)
+}
+
+// handlerName returns the type name of Controller or the function name of
+// Handler.
+func (r *Route) handlerName() string {
+	if r.Handler != nil {
+		if f := runtime.FuncForPC(reflect.ValueOf(r.Handler).Pointer()); f != nil {
+			return f.Name()
+		}
+		return "func"
+	}
+	return fmt.Sprintf("%T", r.Controller)
+}
+
+// shadows reports whether r matches all the requests that other matches, so
+// that other will never be dispatched if r precedes it.
+func (r *Route) shadows(other *Route) bool {
+	if r.pathShape() != other.pathShape() {
+		return false
+	}
+	for i, c := range r.constraints {
+		if !c.covers(other.constraints[i]) {
+			return false
+		}
+	}
+	if r.Host != "" {
+		if len(r.hostLabels) != len(other.hostLabels) {
+			return false
+		}
+		var n, m int
+		for i, label := range r.hostLabels {
+			otherLabel := other.hostLabels[i]
+			switch {
+			case label != "":
+				if label != otherLabel {
+					return false
+				}
+			case otherLabel != "":
+				if c := r.hostConstraints[n]; c != nil && !c.re.MatchString(otherLabel) {
+					return false
+				}
+			default:
+				if !r.hostConstraints[n].covers(other.hostConstraints[m]) {
+					return false
+				}
+			}
+			if label == "" {
+				n++
+			}
+			if otherLabel == "" {
+				m++
+			}
+		}
+	}
+	if r.anyMethod {
+		return true
+	}
+	if other.anyMethod {
+		return false
+	}
+	for _, method := range other.methods {
+		found := false
+		for _, m := range r.methods {
+			if m == method {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// paramConstraintMap is a map of the names of the constraints to the regular
+// expressions.
+var paramConstraintMap = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uint": `[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// paramConstraint represents a constraint of the route parameter.
+type paramConstraint struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+// newParamConstraint returns a new paramConstraint of expr.
+// It returns nil if expr is empty.
+func newParamConstraint(expr string) (*paramConstraint, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	pattern := expr
+	if p, found := paramConstraintMap[expr]; found {
+		pattern = p
+	}
+	re, err := regexp.Compile(`\A(?:` + pattern + `)\z`)
+	if err != nil {
+		return nil, err
+	}
+	return &paramConstraint{expr: expr, re: re}, nil
+}
+
+// covers reports whether c accepts all the values that other accepts.
+// nil means no constraint.
+func (c *paramConstraint) covers(other *paramConstraint) bool {
+	return c == nil || (other != nil && c.expr == other.expr)
+}
+
+// allowedMethods returns the methods that are handled by routes for the Allow
+// header. OPTIONS is always included because it is handled automatically.
+func allowedMethods(routes []*Route) []string {
+	set := make(map[string]bool)
+	for _, route := range routes {
+		for _, method := range route.methods {
+			set[method] = true
+		}
+	}
+	var allowed []string
+	for _, method := range httpMethods {
+		if set[method] {
+			allowed = append(allowed, method)
+			delete(set, method)
+		}
+	}
+	delete(set, "OPTIONS")
+	var others []string
+	for method := range set {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	return append(append(allowed, others...), "OPTIONS")
+}
+
+// splitReverseArgs splits v into the values of the path parameters, the query
+// and the fragment.
+func splitReverseArgs(v []interface{}) (params []interface{}, query url.Values, fragment Fragment) {
+	if n := len(v); n > 0 {
+		if f, ok := v[n-1].(Fragment); ok {
+			fragment, v = f, v[:n-1]
+		}
+	}
+	if n := len(v); n > 0 {
+		if q, ok := toQuery(v[n-1]); ok {
+			query, v = q, v[:n-1]
+		}
+	}
+	return v, query, fragment
+}
+
+// toQuery returns url.Values converted from v.
+// It returns false as ok if v cannot be a query.
+func toQuery(v interface{}) (query url.Values, ok bool) {
+	switch q := v.(type) {
+	case url.Values:
+		return q, true
+	case map[string][]string:
+		return url.Values(q), true
+	case map[string]string:
+		query = make(url.Values, len(q))
+		for key, value := range q {
+			query.Set(key, value)
+		}
+		return query, true
+	case map[string]interface{}:
+		query = make(url.Values, len(q))
+		for key, value := range q {
+			switch value := value.(type) {
+			case []string:
+				query[key] = value
+			default:
+				query.Set(key, fmt.Sprint(value))
+			}
+		}
+		return query, true
+	}
+	return nil, false
+}
+
+// escapePathParam returns the percent-encoded value of the path parameter.
+// The value of the wildcard parameter can contain "/".
+func escapePathParam(kind byte, value string) string {
+	if kind != denco.WildcardCharacter {
+		return url.PathEscape(value)
+	}
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -1,8 +1,14 @@
 package kocha_test
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/naoina/kocha"
@@ -34,6 +40,35 @@ func TestRouter_Reverse(t *testing.T) {
 	}
 }
 
+func TestRouter_Reverse_withQueryAndFragment(t *testing.T) {
+	app := kocha.NewTestApp()
+	for _, v := range []struct {
+		name   string
+		args   []interface{}
+		expect string
+	}{
+		{"root", []interface{}{url.Values{"q": {"go"}, "page": {"2"}}}, "/?page=2&q=go"},
+		{"root", []interface{}{map[string]string{"q": "a&b=c"}}, "/?q=a%26b%3Dc"},
+		{"root", []interface{}{map[string]interface{}{"page": 2, "tag": []string{"a", "b"}}}, "/?page=2&tag=a&tag=b"},
+		{"root", []interface{}{url.Values{}}, "/"},
+		{"root", []interface{}{kocha.Fragment("top")}, "/#top"},
+		{"user", []interface{}{77, url.Values{"tab": {"posts"}}, kocha.Fragment("section 1")}, "/user/77?tab=posts#section%201"},
+		{"user", []interface{}{"a b/c?d"}, "/user/a%20b%2Fc%3Fd"},
+		{"static", []interface{}{"dir name/hoge.png"}, "/static/dir%20name/hoge.png"},
+	} {
+		r, err := app.Router.Reverse(v.name, v.args...)
+		if err != nil {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (_, %#v); want (_, nil)`, v.name, v.args, err)
+			continue
+		}
+		actual := r
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (%#v, nil); want (%#v, nil)`, v.name, v.args, actual, expect)
+		}
+	}
+}
+
 func TestRouter_Reverse_withUnknownRouteName(t *testing.T) {
 	app := kocha.NewTestApp()
 	name := "unknown"
@@ -67,3 +102,621 @@ func TestRouter_Reverse_withManyArguments(t *testing.T) {
 		t.Errorf(`Router.Reverse(%#v, %#v) => (_, %#v); want (_, %#v)`, name, args, actual, expect)
 	}
 }
+
+func newTestMethodRoutingApp(t *testing.T) *kocha.Application {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	config.RouteTable = kocha.RouteTable{
+		{
+			Name:   "show_user",
+			Path:   "/users/:id",
+			Method: "GET",
+			Handler: func(c *kocha.Context) error {
+				return c.RenderText("show " + c.Params.Get("id"))
+			},
+		},
+		{
+			Name:   "delete_user",
+			Path:   "/users/:id",
+			Method: "DELETE",
+			Handler: func(c *kocha.Context) error {
+				return c.RenderText("delete " + c.Params.Get("id"))
+			},
+		},
+		{
+			Name:       "root",
+			Path:       "/",
+			Controller: &kocha.FixtureRootTestCtrl{},
+		},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestRouter_withMethodRoutes(t *testing.T) {
+	app := newTestMethodRoutingApp(t)
+	for _, v := range []struct {
+		method string
+		status int
+		body   string
+	}{
+		{"GET", http.StatusOK, "show 7"},
+		{"DELETE", http.StatusOK, "delete 7"},
+		{"POST", http.StatusMethodNotAllowed, ""},
+	} {
+		req, err := http.NewRequest(v.method, "/users/7", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = v.status
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v /users/7 status => %#v; want %#v`, v.method, actual, expect)
+		}
+		if v.body == "" {
+			continue
+		}
+		actual = w.Body.String()
+		expect = v.body
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v /users/7 => %#v; want %#v`, v.method, actual, expect)
+		}
+	}
+}
+
+func TestRouter_withUnsupportedMethod(t *testing.T) {
+	for _, v := range []struct {
+		app    *kocha.Application
+		method string
+		uri    string
+		status int
+		allow  string
+	}{
+		{kocha.NewTestApp(), "POST", "/user/7", http.StatusMethodNotAllowed, "GET, OPTIONS"},
+		{kocha.NewTestApp(), "OPTIONS", "/user/7", http.StatusOK, "GET, OPTIONS"},
+		{kocha.NewTestApp(), "DELETE", "/2013/07/19/user/naoina", http.StatusMethodNotAllowed, "GET, OPTIONS"},
+		{kocha.NewTestApp(), "GET", "/post_test", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{kocha.NewTestApp(), "POST", "/missing", http.StatusNotFound, ""},
+		{kocha.NewTestApp(), "OPTIONS", "/missing", http.StatusNotFound, ""},
+		{newTestMethodRoutingApp(t), "PUT", "/users/7", http.StatusMethodNotAllowed, "GET, DELETE, OPTIONS"},
+		{newTestMethodRoutingApp(t), "OPTIONS", "/users/7", http.StatusOK, "GET, DELETE, OPTIONS"},
+	} {
+		req, err := http.NewRequest(v.method, v.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		v.app.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = v.status
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v status => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+		actual = w.Header().Get("Allow")
+		expect = v.allow
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%v %v Allow => %#v; want %#v`, v.method, v.uri, actual, expect)
+		}
+	}
+}
+
+func TestRouter_Reverse_withMethodRoutes(t *testing.T) {
+	app := newTestMethodRoutingApp(t)
+	for _, name := range []string{"show_user", "delete_user"} {
+		r, err := app.Router.Reverse(name, 7)
+		if err != nil {
+			t.Errorf(`Router.Reverse(%#v, 7) => (_, %#v); want (_, nil)`, name, err)
+			continue
+		}
+		actual := r
+		expect := "/users/7"
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, 7) => (%#v, nil); want (%#v, nil)`, name, actual, expect)
+		}
+	}
+
+	_, err := app.Router.Reverse("show_user")
+	actual := err
+	expect := fmt.Errorf("kocha: too few arguments: %s (handler is GET /users/:id)", "show_user")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`Router.Reverse(%#v) => (_, %#v); want (_, %#v)`, "show_user", actual, expect)
+	}
+}
+
+func TestNew_withInvalidRoute(t *testing.T) {
+	handler := func(c *kocha.Context) error { return nil }
+	for _, v := range []struct {
+		route  *kocha.Route
+		expect error
+	}{
+		{&kocha.Route{Name: "r", Path: "/", Handler: handler}, fmt.Errorf("kocha: route r: Method must be specified with Handler")},
+		{&kocha.Route{Name: "r", Path: "/", Method: "GET"}, fmt.Errorf("kocha: route r: Handler must be specified with Method")},
+		{&kocha.Route{Name: "r", Path: "/", Method: "GET", Handler: handler, Controller: &kocha.FixtureRootTestCtrl{}}, fmt.Errorf("kocha: route r: Controller and Handler cannot be specified together")},
+		{&kocha.Route{Name: "r", Path: "/"}, fmt.Errorf("kocha: route r: Controller or Handler must be specified")},
+	} {
+		config := newConfig()
+		config.RouteTable = kocha.RouteTable{v.route}
+		_, err := kocha.New(config)
+		actual := err
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`kocha.New(%#v) => (_, %#v); want (_, %#v)`, v.route, actual, expect)
+		}
+	}
+}
+
+type testHeaderMiddleware struct {
+	value string
+}
+
+func (m *testHeaderMiddleware) Process(app *kocha.Application, c *kocha.Context, next func() error) error {
+	c.Response.Header().Add("X-Test-Middleware", m.value)
+	return next()
+}
+
+func TestRouteGroup_RouteTable(t *testing.T) {
+	m1, m2, m3 := &testHeaderMiddleware{"1"}, &testHeaderMiddleware{"2"}, &testHeaderMiddleware{"3"}
+	ctrl := &kocha.FixtureRootTestCtrl{}
+	inner := &kocha.RouteGroup{
+		Prefix:      "/v1/",
+		Middlewares: []kocha.Middleware{m2},
+		Routes: kocha.RouteTable{
+			{Name: "api_root", Path: "/", Controller: ctrl},
+			{Name: "api_user", Path: "/users/:id", Controller: ctrl, Middlewares: []kocha.Middleware{m3}},
+		},
+	}
+	outer := &kocha.RouteGroup{
+		Prefix:      "/api",
+		Middlewares: []kocha.Middleware{m1},
+		Routes:      inner.RouteTable(),
+	}
+	actual := outer.RouteTable()
+	expect := kocha.RouteTable{
+		{Name: "api_root", Path: "/api/v1", Controller: ctrl, Middlewares: []kocha.Middleware{m1, m2}},
+		{Name: "api_user", Path: "/api/v1/users/:id", Controller: ctrl, Middlewares: []kocha.Middleware{m1, m2, m3}},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`RouteGroup.RouteTable() => %#v; want %#v`, actual, expect)
+	}
+
+	// the original routes must not be changed.
+	var actualPath interface{} = inner.Routes[1].Path
+	var expectPath interface{} = "/users/:id"
+	if !reflect.DeepEqual(actualPath, expectPath) {
+		t.Errorf(`RouteGroup.RouteTable(); Routes[1].Path => %#v; want %#v`, actualPath, expectPath)
+	}
+}
+
+func TestRouteGroup_withMiddlewares(t *testing.T) {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	handler := func(c *kocha.Context) error {
+		return c.RenderText(c.Name)
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: handler},
+	}.Append((&kocha.RouteGroup{
+		Prefix:      "/admin",
+		Middlewares: []kocha.Middleware{&testHeaderMiddleware{"admin"}},
+		Routes: kocha.RouteTable{
+			{Name: "admin_root", Path: "/", Method: "GET", Handler: handler},
+			{Name: "admin_users", Path: "/users", Method: "GET", Handler: handler},
+		},
+	}).RouteTable())
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		uri    string
+		status int
+		body   string
+		header []string
+	}{
+		{"/", http.StatusOK, "root", nil},
+		{"/admin", http.StatusOK, "admin_root", []string{"admin"}},
+		{"/admin/users", http.StatusOK, "admin_users", []string{"admin"}},
+		{"/admin/missing", http.StatusNotFound, "", nil},
+	} {
+		req, err := http.NewRequest("GET", v.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = v.status
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v status => %#v; want %#v`, v.uri, actual, expect)
+		}
+		actual = w.Header()["X-Test-Middleware"]
+		expect = v.header
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v X-Test-Middleware => %#v; want %#v`, v.uri, actual, expect)
+		}
+		if v.body == "" {
+			continue
+		}
+		actual = w.Body.String()
+		expect = v.body
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v => %#v; want %#v`, v.uri, actual, expect)
+		}
+	}
+}
+
+func TestRouteGroup_withSessionMiddleware(t *testing.T) {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	handler := func(c *kocha.Context) error {
+		c.Session["name"] = c.Name
+		return c.RenderText(c.Name)
+	}
+	config.RouteTable = (&kocha.RouteGroup{
+		Prefix: "/admin",
+		Middlewares: []kocha.Middleware{&kocha.SessionMiddleware{
+			Name: "test_session",
+			Store: &kocha.SessionCookieStore{
+				SecretKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", 32))),
+				SigningKey: base64.StdEncoding.EncodeToString([]byte("signing key")),
+			},
+		}},
+		Routes: kocha.RouteTable{
+			{Name: "admin_root", Path: "/", Method: "GET", Handler: handler},
+			{Name: "admin_users", Path: "/users", Method: "GET", Handler: handler},
+		},
+	}).RouteTable()
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range []string{"/admin", "/admin/users"} {
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = http.StatusOK
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v status => %#v; want %#v`, uri, actual, expect)
+		}
+		actual = strings.HasPrefix(w.Header().Get("Set-Cookie"), "test_session=")
+		expect = true
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v Set-Cookie => %#v; want session cookie`, uri, w.Header().Get("Set-Cookie"))
+		}
+	}
+}
+
+func newTestConstraintApp(t *testing.T) *kocha.Application {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	handler := func(c *kocha.Context) error {
+		return c.RenderText(c.Name + " " + c.Params.Encode())
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "user", Path: "/users/:id<int>", Method: "GET", Handler: handler},
+		{Name: "user_by_slug", Path: "/users/:slug<[a-z-]+>", Method: "GET", Handler: handler},
+		{Name: "token", Path: "/tokens/:uuid<uuid>", Method: "GET", Handler: handler},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestRouter_withConstraints(t *testing.T) {
+	app := newTestConstraintApp(t)
+	for _, v := range []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/users/10", http.StatusOK, "user id=10"},
+		{"/users/-3", http.StatusOK, "user id=-3"},
+		{"/users/naoina-n", http.StatusOK, "user_by_slug slug=naoina-n"},
+		{"/users/Naoina", http.StatusNotFound, ""},
+		{"/users/10a", http.StatusNotFound, ""},
+		{"/tokens/0f8fad5b-d9cb-469f-a165-70867728950e", http.StatusOK, "token uuid=0f8fad5b-d9cb-469f-a165-70867728950e"},
+		{"/tokens/0f8fad5b", http.StatusNotFound, ""},
+	} {
+		req, err := http.NewRequest("GET", v.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var actual interface{} = w.Code
+		var expect interface{} = v.status
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v status => %#v; want %#v`, v.uri, actual, expect)
+		}
+		if v.body == "" {
+			continue
+		}
+		actual = w.Body.String()
+		expect = v.body
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v => %#v; want %#v`, v.uri, actual, expect)
+		}
+	}
+}
+
+func TestRouter_Reverse_withConstraints(t *testing.T) {
+	app := newTestConstraintApp(t)
+	for _, v := range []struct {
+		name   string
+		arg    interface{}
+		expect string
+		err    error
+	}{
+		{"user", 10, "/users/10", nil},
+		{"user", "abc", "", fmt.Errorf("kocha: invalid argument %q for %v<%v>: %v", "abc", ":id", "int", "user")},
+		{"user_by_slug", "naoina", "/users/naoina", nil},
+		{"user_by_slug", "Naoina", "", fmt.Errorf("kocha: invalid argument %q for %v<%v>: %v", "Naoina", ":slug", "[a-z-]+", "user_by_slug")},
+		{"token", "0f8fad5b-d9cb-469f-a165-70867728950e", "/tokens/0f8fad5b-d9cb-469f-a165-70867728950e", nil},
+	} {
+		r, err := app.Router.Reverse(v.name, v.arg)
+		var actual interface{} = err
+		var expect interface{} = v.err
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (_, %#v); want (_, %#v)`, v.name, v.arg, actual, expect)
+		}
+		actual = r
+		expect = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (%#v, _); want (%#v, _)`, v.name, v.arg, actual, expect)
+		}
+	}
+}
+
+func TestNew_withInvalidConstraint(t *testing.T) {
+	for _, v := range []struct {
+		path   string
+		expect string
+	}{
+		{"/users/:id<int", "kocha: route r: invalid constraint: :id<int"},
+		{"/users/:id<(>", "kocha: route r: invalid constraint: :id<(>: error parsing regexp: "},
+	} {
+		config := newConfig()
+		config.RouteTable = kocha.RouteTable{
+			{Name: "r", Path: v.path, Controller: &kocha.FixtureRootTestCtrl{}},
+		}
+		_, err := kocha.New(config)
+		if err == nil || !strings.HasPrefix(err.Error(), v.expect) {
+			t.Errorf(`kocha.New(%#v) => (_, %#v); want (_, %#v...)`, v.path, err, v.expect)
+		}
+	}
+}
+
+func newTestHostRoutingApp(t *testing.T) *kocha.Application {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.DispatchMiddleware{}}
+	handler := func(c *kocha.Context) error {
+		return c.RenderText(c.Name + " " + c.Params.Encode())
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "api_user", Host: "api.example.com", Scheme: "https", Path: "/users/:id", Method: "GET", Handler: handler},
+		{Name: "tenant_user", Host: ":tenant<[a-z]+>.example.com", Path: "/users/:id", Method: "GET", Handler: handler},
+		{Name: "user", Path: "/users/:id", Method: "GET", Handler: handler},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestRouter_withHost(t *testing.T) {
+	app := newTestHostRoutingApp(t)
+	for _, v := range []struct {
+		host string
+		body string
+	}{
+		{"api.example.com", "api_user id=7"},
+		{"API.Example.com:9100", "api_user id=7"},
+		{"acme.example.com", "tenant_user id=7&tenant=acme"},
+		{"acme.example.com:9100", "tenant_user id=7&tenant=acme"},
+		{"acme1.example.com", "user id=7"},
+		{"www.acme.example.com", "user id=7"},
+		{"example.com", "user id=7"},
+	} {
+		req, err := http.NewRequest("GET", "/users/7", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = v.host
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		actual := w.Body.String()
+		expect := v.body
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET %v/users/7 => %#v; want %#v`, v.host, actual, expect)
+		}
+	}
+}
+
+func TestRouter_Reverse_withHost(t *testing.T) {
+	app := newTestHostRoutingApp(t)
+	for _, v := range []struct {
+		name   string
+		args   []interface{}
+		expect string
+		err    error
+	}{
+		{"api_user", []interface{}{7}, "https://api.example.com/users/7", nil},
+		{"tenant_user", []interface{}{"acme", 7, url.Values{"q": {"go"}}}, "http://acme.example.com/users/7?q=go", nil},
+		{"user", []interface{}{7}, "/users/7", nil},
+		{"tenant_user", []interface{}{}, "", fmt.Errorf("kocha: too few arguments: tenant_user (handler is GET /users/:id)")},
+		{"tenant_user", []interface{}{"acme"}, "", fmt.Errorf("kocha: too few arguments: tenant_user (handler is GET /users/:id)")},
+		{"tenant_user", []interface{}{"Acme", 7}, "", fmt.Errorf("kocha: invalid argument %q for %v<%v>: %v", "Acme", ":tenant", "[a-z]+", "tenant_user")},
+	} {
+		r, err := app.Router.Reverse(v.name, v.args...)
+		var actual interface{} = err
+		var expect interface{} = v.err
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (_, %#v); want (_, %#v)`, v.name, v.args, actual, expect)
+		}
+		actual = r
+		expect = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Router.Reverse(%#v, %#v) => (%#v, _); want (%#v, _)`, v.name, v.args, actual, expect)
+		}
+	}
+}
+
+func TestRouteTable_Inspect(t *testing.T) {
+	handler := func(c *kocha.Context) error { return nil }
+	rt := kocha.RouteTable{
+		{Name: "root", Path: "/", Controller: &kocha.FixtureRootTestCtrl{}},
+		{Name: "user", Path: "/users/:id<int>", Method: "GET", Handler: handler},
+		{Name: "user_by_name", Path: "/users/:name", Method: "GET", Handler: handler},
+		{Name: "user_again", Path: "/users/:uid<int>", Method: "GET", Handler: handler},
+		{Name: "tenant_user", Host: ":tenant.example.com", Path: "/users/:id", Method: "DELETE", Handler: handler},
+		{Name: "api_user", Host: "api.example.com", Path: "/users/:id", Method: "DELETE", Handler: handler},
+		{Name: "root", Path: "/root", Controller: &kocha.FixtureRootTestCtrl{}},
+		{Name: "nothing", Path: "/nothing", Controller: &kocha.DefaultController{}},
+	}
+	infos, warnings, err := rt.Inspect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// name of the function literal depends on the compiler.
+	if !strings.Contains(infos[1].Handler, "TestRouteTable_Inspect") {
+		t.Errorf(`RouteTable.Inspect(); Handler of %v => %#v; want name of handler`, infos[1].Name, infos[1].Handler)
+	}
+	infos[1].Handler = ""
+	var actual interface{} = infos[:2]
+	var expect interface{} = []kocha.RouteInfo{
+		{Name: "root", Methods: []string{"GET"}, Path: "/", ParamNames: []string{}, Handler: "*kocha.FixtureRootTestCtrl"},
+		{Name: "user", Methods: []string{"GET"}, Path: "/users/:id<int>", ParamNames: []string{":id"}},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`RouteTable.Inspect() => (%#v, _, nil); want (%#v, _, nil)`, actual, expect)
+	}
+	actual = infos[4].ParamNames
+	expect = []string{":tenant", ":id"}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`RouteTable.Inspect(); ParamNames of %v => %#v; want %#v`, infos[4].Name, actual, expect)
+	}
+	actual = warnings
+	expect = []string{
+		"route user_again is unreachable because route user precedes it",
+		"route api_user is unreachable because route tenant_user precedes it",
+		"route name root is duplicated",
+		"route nothing is unreachable because it handles no methods",
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`RouteTable.Inspect() => (_, %#v, nil); want (_, %#v, nil)`, actual, expect)
+	}
+}
+
+type testValueReceiverCtrl struct {
+	*kocha.DefaultController
+}
+
+func (ctrl testValueReceiverCtrl) PUT(c *kocha.Context) error {
+	return nil
+}
+
+type testNestedCtrl struct {
+	testValueReceiverCtrl
+}
+
+func (ctrl *testNestedCtrl) DELETE(c *kocha.Context) error {
+	return nil
+}
+
+func TestRouteTable_Inspect_withEmbeddedDefaultController(t *testing.T) {
+	rt := kocha.RouteTable{
+		{Name: "value", Path: "/value", Controller: testValueReceiverCtrl{}},
+		{Name: "value_ptr", Path: "/value_ptr", Controller: &testValueReceiverCtrl{}},
+		{Name: "nested", Path: "/nested", Controller: &testNestedCtrl{}},
+		{Name: "post", Path: "/post", Controller: &kocha.FixturePostTestCtrl{}},
+	}
+	infos, _, err := rt.Inspect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual [][]string
+	for _, info := range infos {
+		actual = append(actual, info.Methods)
+	}
+	expect := [][]string{
+		{"PUT"},
+		{"PUT"},
+		{"PUT", "DELETE"},
+		{"POST"},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`RouteTable.Inspect(); Methods => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestRouteTable_Inspect_withUnnamedRoutes(t *testing.T) {
+	handler := func(c *kocha.Context) error { return nil }
+	rt := kocha.RouteTable{
+		{Path: "/foo", Method: "GET", Handler: handler},
+		{Path: "/bar", Method: "GET", Handler: handler},
+	}
+	_, warnings, err := rt.Inspect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf(`RouteTable.Inspect() with unnamed routes => (_, %#v, nil); want (_, [], nil)`, warnings)
+	}
+}
+
+func TestNew_withConflictingRoutes(t *testing.T) {
+	handler := func(c *kocha.Context) error { return nil }
+	for _, v := range []struct {
+		routes kocha.RouteTable
+		expect error
+	}{
+		{kocha.RouteTable{
+			{Name: "user", Path: "/users/:id", Controller: &kocha.FixtureUserTestCtrl{}},
+			{Name: "user", Path: "/people/:id", Controller: &kocha.FixtureUserTestCtrl{}},
+		}, fmt.Errorf("kocha: route name user is duplicated: /users/:id and /people/:id")},
+		{kocha.RouteTable{
+			{Name: "user", Path: "/users/:id", Controller: &kocha.FixtureUserTestCtrl{}},
+			{Name: "user_by_name", Path: "/users/:name", Method: "GET", Handler: handler},
+		}, fmt.Errorf("kocha: route user_by_name is unreachable because route user precedes it: /users/:id and /users/:name")},
+		{kocha.RouteTable{
+			{Name: "user", Path: "/users/:id", Method: "GET", Handler: handler},
+			{Name: "user_by_int", Path: "/users/:id<int>", Method: "GET", Handler: handler},
+		}, fmt.Errorf("kocha: route user_by_int is unreachable because route user precedes it: /users/:id and /users/:id<int>")},
+		{kocha.RouteTable{
+			{Name: "user", Path: "/users/:id<int>", Method: "GET", Handler: handler},
+			{Name: "user_by_name", Path: "/users/:name", Method: "GET", Handler: handler},
+			{Name: "api_user", Host: "api.example.com", Path: "/users/:id", Method: "DELETE", Handler: handler},
+			{Name: "delete_user", Path: "/users/:id", Method: "DELETE", Handler: handler},
+		}, nil},
+		{kocha.RouteTable{
+			{Name: "delete_user", Path: "/users/:id", Method: "DELETE", Handler: handler},
+			{Name: "api_user", Host: "api.example.com", Path: "/users/:id", Method: "DELETE", Handler: handler},
+		}, fmt.Errorf("kocha: route api_user is unreachable because route delete_user precedes it: /users/:id and /users/:id")},
+	} {
+		config := newConfig()
+		config.RouteTable = v.routes
+		_, err := kocha.New(config)
+		var actual interface{} = err
+		var expect interface{} = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`kocha.New(%#v) => (_, %#v); want (_, %#v)`, v.routes, actual, expect)
+		}
+	}
+}
@@ -8,10 +8,18 @@ import (
 	"crypto/rand"
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/naoina/kocha/util"
 	"github.com/ugorji/go/codec"
 )
 
@@ -21,6 +29,14 @@ type SessionStore interface {
 	Load(key string) (sess Session, err error)
 }
 
+// SessionDeleter is the interface that the session store that can delete
+// the session data.
+// SessionMiddleware calls Delete with the old key when the session is
+// regenerated or destroyed.
+type SessionDeleter interface {
+	Delete(key string) error
+}
+
 // Session represents a session data store.
 type Session map[string]string
 
@@ -66,14 +82,45 @@ func NewErrSession(msg string) error {
 //
 // This session store will be a session save to client-side cookie.
 // Session cookie for save is encoded, encrypted and signed.
+//
+// If Key is specified, session cookie is encrypted and authenticated with
+// only Key by AES-GCM, and the cookie name is bound to it as the associated
+// data. Otherwise, it is encrypted with SecretKey and signed with SigningKey.
+// The session cookies that were saved in either format are loaded while the
+// keys are still specified, so that SecretKey and SigningKey can be left for
+// the migration to Key.
 type SessionCookieStore struct {
+	// Key for the AEAD encryption.
+	Key string
+
 	// key for the encryption.
 	SecretKey string
 
 	// Key for the cookie singing.
 	SigningKey string
+
+	// Previous keys for the key rotation.
+	// The session cookies that were saved with any of them are still loaded,
+	// and they will be saved with the current keys again by SessionMiddleware.
+	PreviousKeys []SessionCookieKeys
+
+	// Name of the session cookie that is bound to the session cookie as the
+	// associated data of the AEAD encryption.
+	// If empty, SessionMiddleware.Name is used.
+	CookieName string
+}
+
+// SessionCookieKeys represents a set of the keys of SessionCookieStore.
+type SessionCookieKeys struct {
+	Key        string
+	SecretKey  string
+	SigningKey string
 }
 
+// sessionCookieVersionAEAD is the format version byte of the session cookie
+// that is encrypted with Key.
+const sessionCookieVersionAEAD byte = 1
+
 var codecHandler = &codec.MsgpackHandle{}
 
 // Save saves and returns the key of session cookie.
@@ -87,6 +134,13 @@ func (store *SessionCookieStore) Save(sess Session) (key string, err error) {
 	if err := codec.NewEncoder(buf, codecHandler).Encode(sess); err != nil {
 		return "", err
 	}
+	if store.Key != "" {
+		sealed, err := store.seal(buf.Bytes())
+		if err != nil {
+			return "", err
+		}
+		return store.encode(sealed), nil
+	}
 	encrypted, err := store.encrypt(buf.Bytes())
 	if err != nil {
 		return "", err
@@ -101,46 +155,145 @@ func (store *SessionCookieStore) Load(key string) (sess Session, err error) {
 	if err != nil {
 		return nil, err
 	}
-	unsigned, err := store.verify(decoded)
-	if err != nil {
-		return nil, err
-	}
-	decrypted, err := store.decrypt(unsigned)
+	data, err := store.open(decoded)
 	if err != nil {
-		return nil, err
+		// the session cookie may be saved in the legacy format, which can
+		// also begin with the version byte by chance.
+		keys, unsigned, err := store.verify(decoded)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = store.decrypt(keys.SecretKey, unsigned); err != nil {
+			return nil, err
+		}
 	}
-	if err := codec.NewDecoderBytes(decrypted, codecHandler).Decode(&sess); err != nil {
+	if err := codec.NewDecoderBytes(data, codecHandler).Decode(&sess); err != nil {
 		return nil, err
 	}
 	return sess, nil
 }
 
-// Validate validates SecretKey size.
+// Validate validates Key and SecretKey size.
+// Also the keys of PreviousKeys are validated.
 func (store *SessionCookieStore) Validate() error {
-	b, err := base64.StdEncoding.DecodeString(store.SecretKey)
+	if err := validateSessionCookieKeys(&store.Key, &store.SecretKey, &store.SigningKey); err != nil {
+		return fmt.Errorf("kocha: session: %T.%v", *store, err)
+	}
+	for i := range store.PreviousKeys {
+		keys := &store.PreviousKeys[i]
+		if err := validateSessionCookieKeys(&keys.Key, &keys.SecretKey, &keys.SigningKey); err != nil {
+			return fmt.Errorf("kocha: session: %T.PreviousKeys[%d].%v", *store, i, err)
+		}
+	}
+	return nil
+}
+
+// validateSessionCookieKeys decodes the keys by Base64, and then validates
+// the size of key and secretKey.
+// secretKey and signingKey can be omitted if key is specified.
+func validateSessionCookieKeys(key, secretKey, signingKey *string) error {
+	if *key != "" {
+		b, err := base64.StdEncoding.DecodeString(*key)
+		if err != nil {
+			return fmt.Errorf("Key: %v", err)
+		}
+		*key = string(b)
+		if !isValidAESKeySize(len(*key)) {
+			return fmt.Errorf("Key size must be 16, 24 or 32, but %v", len(*key))
+		}
+		if *secretKey == "" && *signingKey == "" {
+			return nil
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(*secretKey)
 	if err != nil {
-		return err
+		return fmt.Errorf("SecretKey: %v", err)
 	}
-	store.SecretKey = string(b)
-	b, err = base64.StdEncoding.DecodeString(store.SigningKey)
+	*secretKey = string(b)
+	b, err = base64.StdEncoding.DecodeString(*signingKey)
 	if err != nil {
-		return err
+		return fmt.Errorf("SigningKey: %v", err)
+	}
+	*signingKey = string(b)
+	if !isValidAESKeySize(len(*secretKey)) {
+		return fmt.Errorf("SecretKey size must be 16, 24 or 32, but %v", len(*secretKey))
 	}
-	store.SigningKey = string(b)
-	switch len(store.SecretKey) {
+	return nil
+}
+
+// isValidAESKeySize returns whether the size is valid as the key of AES.
+func isValidAESKeySize(size int) bool {
+	switch size {
 	case 16, 24, 32:
-		return nil
+		return true
 	}
-	return fmt.Errorf("kocha: session: %T.SecretKey size must be 16, 24 or 32, but %v", *store, len(store.SecretKey))
+	return false
 }
 
-// encrypt returns encrypted data by AES-256-CBC.
-func (store *SessionCookieStore) encrypt(buf []byte) ([]byte, error) {
-	block, err := aes.NewCipher([]byte(store.SecretKey))
+// keys returns the current keys and the keys of PreviousKeys.
+func (store *SessionCookieStore) keys() []SessionCookieKeys {
+	current := SessionCookieKeys{Key: store.Key, SecretKey: store.SecretKey, SigningKey: store.SigningKey}
+	return append([]SessionCookieKeys{current}, store.PreviousKeys...)
+}
+
+// seal returns the data that is encrypted and authenticated with Key by
+// AES-GCM. The data consists of the version byte, nonce and ciphertext.
+func (store *SessionCookieStore) seal(buf []byte) ([]byte, error) {
+	aead, err := newAESGCM(store.Key)
 	if err != nil {
 		return nil, err
 	}
-	aead, err := cipher.NewGCM(block)
+	sealed := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(buf)+aead.Overhead())
+	sealed[0] = sessionCookieVersionAEAD
+	nonce := sealed[1:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(sealed, nonce, buf, store.additionalData()), nil
+}
+
+// open returns the decrypted data from the data that is sealed by seal.
+// The data is opened by Key and the keys of PreviousKeys in order.
+func (store *SessionCookieStore) open(src []byte) ([]byte, error) {
+	if len(src) == 0 || src[0] != sessionCookieVersionAEAD {
+		return nil, errors.New("kocha: session cookie version mismatch")
+	}
+	for _, keys := range store.keys() {
+		if keys.Key == "" {
+			continue
+		}
+		aead, err := newAESGCM(keys.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(src) < 1+aead.NonceSize()+aead.Overhead() {
+			return nil, errors.New("kocha: session cookie value too short")
+		}
+		nonce, sealed := src[1:1+aead.NonceSize()], src[1+aead.NonceSize():]
+		if data, err := aead.Open(nil, nonce, sealed, store.additionalData()); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("kocha: session cookie verification failed")
+}
+
+// additionalData returns the associated data of the AEAD encryption.
+func (store *SessionCookieStore) additionalData() []byte {
+	return append([]byte{sessionCookieVersionAEAD}, store.CookieName...)
+}
+
+// newAESGCM returns AES-GCM with the key.
+func newAESGCM(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns encrypted data by AES-256-CBC.
+func (store *SessionCookieStore) encrypt(buf []byte) ([]byte, error) {
+	aead, err := newAESGCM(store.SecretKey)
 	if err != nil {
 		return nil, err
 	}
@@ -153,12 +306,8 @@ func (store *SessionCookieStore) encrypt(buf []byte) ([]byte, error) {
 }
 
 // decrypt returns decrypted data from crypted data by AES-256-CBC.
-func (store *SessionCookieStore) decrypt(buf []byte) ([]byte, error) {
-	block, err := aes.NewCipher([]byte(store.SecretKey))
-	if err != nil {
-		return nil, err
-	}
-	aead, err := cipher.NewGCM(block)
+func (store *SessionCookieStore) decrypt(secretKey string, buf []byte) ([]byte, error) {
+	aead, err := newAESGCM(secretKey)
 	if err != nil {
 		return nil, err
 	}
@@ -202,26 +351,483 @@ func (store *SessionCookieStore) decode(src string) ([]byte, error) {
 
 // sign returns signed data.
 func (store *SessionCookieStore) sign(src []byte) []byte {
-	sign := store.hash(src)
+	sign := store.hash(store.SigningKey, src)
 	return append(sign, src...)
 }
 
 // verify verify signed data and returns unsigned data if valid.
-func (store *SessionCookieStore) verify(src []byte) (unsigned []byte, err error) {
+// The signature is verified by SigningKey and the signing keys of
+// PreviousKeys in order, and it returns the keys that verified it.
+func (store *SessionCookieStore) verify(src []byte) (keys SessionCookieKeys, unsigned []byte, err error) {
 	if len(src) <= sha512.Size256 {
-		return nil, errors.New("kocha: session cookie value too short")
+		return keys, nil, errors.New("kocha: session cookie value too short")
 	}
 	sign := src[:sha512.Size256]
 	unsigned = src[sha512.Size256:]
-	if !hmac.Equal(store.hash(unsigned), sign) {
-		return nil, errors.New("kocha: session cookie verification failed")
+	for _, keys := range store.keys() {
+		if keys.SecretKey == "" {
+			continue
+		}
+		if hmac.Equal(store.hash(keys.SigningKey, unsigned), sign) {
+			return keys, unsigned, nil
+		}
 	}
-	return unsigned, nil
+	return keys, nil, errors.New("kocha: session cookie verification failed")
 }
 
 // hash returns hashed data by HMAC-SHA512/256.
-func (store *SessionCookieStore) hash(src []byte) []byte {
-	hash := hmac.New(sha512.New512_256, []byte(store.SigningKey))
+func (store *SessionCookieStore) hash(signingKey string, src []byte) []byte {
+	hash := hmac.New(sha512.New512_256, []byte(signingKey))
 	hash.Write(src)
 	return hash.Sum(nil)
 }
+
+// sessionIDKey is the key of the session ID in the session data of
+// SessionKVSStore.
+const sessionIDKey = "_kocha._sess._id"
+
+// SessionKVS is the interface that the key-value storage for SessionKVSStore.
+type SessionKVS interface {
+	// Get returns the value associated with the key.
+	// If the value doesn't exist or has been expired, it returns nil and no error.
+	Get(key string) (value []byte, err error)
+
+	// Set sets the value associated with the key.
+	// The value should be expired after expires. 0 is for persistent.
+	Set(key string, value []byte, expires time.Duration) error
+
+	// Del deletes the value associated with the key.
+	Del(key string) error
+}
+
+// Implementation of server-side session store.
+//
+// This session store will be a session save to SessionKVS, and only the
+// opaque random session ID will be saved to the client-side cookie.
+type SessionKVSStore struct {
+	// Key-value storage to save the session data.
+	KVS SessionKVS
+
+	// Expiration of session data in KVS, from the last save.
+	// If 0, SessionMiddleware.SessionExpires is used.
+	// It must be positive so that the session data doesn't remain in KVS
+	// forever.
+	Expires time.Duration
+}
+
+// Save saves the session data to KVS, and returns the session ID as the key
+// of session cookie.
+// The session ID is generated at the first save of the session.
+func (store *SessionKVSStore) Save(sess Session) (key string, err error) {
+	id := sess[sessionIDKey]
+	if id == "" {
+		if id, err = newSessionID(); err != nil {
+			return "", err
+		}
+	}
+	data := make(Session, len(sess))
+	for k, v := range sess {
+		if k != sessionIDKey {
+			data[k] = v
+		}
+	}
+	var buf []byte
+	if err := codec.NewEncoderBytes(&buf, codecHandler).Encode(data); err != nil {
+		return "", err
+	}
+	if err := store.KVS.Set(id, buf, store.Expires); err != nil {
+		return "", err
+	}
+	sess[sessionIDKey] = id
+	return id, nil
+}
+
+// Load returns the session data that associated with the session ID.
+// The key is stored session cookie value.
+func (store *SessionKVSStore) Load(key string) (sess Session, err error) {
+	if !isValidSessionID(key) {
+		return nil, NewErrSession("invalid session ID")
+	}
+	buf, err := store.KVS.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if buf == nil {
+		return nil, NewErrSession("session not found")
+	}
+	if err := codec.NewDecoderBytes(buf, codecHandler).Decode(&sess); err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		sess = make(Session)
+	}
+	sess[sessionIDKey] = key
+	return sess, nil
+}
+
+// Delete deletes the session data that associated with the session ID.
+func (store *SessionKVSStore) Delete(key string) error {
+	if !isValidSessionID(key) {
+		return nil
+	}
+	return store.KVS.Del(key)
+}
+
+// Validate validates KVS and Expires.
+func (store *SessionKVSStore) Validate() error {
+	if store.KVS == nil {
+		return fmt.Errorf("kocha: session: %T.KVS must be specified", *store)
+	}
+	if v, ok := store.KVS.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if store.Expires <= 0 {
+		return fmt.Errorf("kocha: session: %T.Expires or SessionMiddleware.SessionExpires must be positive", *store)
+	}
+	return nil
+}
+
+// newSessionID returns a new random session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isValidSessionID returns whether the id consists of the characters of
+// Base64 with URLEncoding.
+func isValidSessionID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SessionMemoryKVS is an in-memory implementation of SessionKVS.
+// The data will be lost when the application exits.
+type SessionMemoryKVS struct {
+	mu      sync.Mutex
+	entries map[string]sessionMemoryEntry
+	done    chan struct{}
+}
+
+type sessionMemoryEntry struct {
+	value   []byte
+	expires time.Time // zero if persistent.
+}
+
+// NewSessionMemoryKVS returns a new SessionMemoryKVS.
+// If sweepInterval is greater than 0, the expired data will be swept at
+// every sweepInterval until Close is called.
+func NewSessionMemoryKVS(sweepInterval time.Duration) *SessionMemoryKVS {
+	kvs := &SessionMemoryKVS{
+		entries: make(map[string]sessionMemoryEntry),
+		done:    make(chan struct{}),
+	}
+	if sweepInterval > 0 {
+		go kvs.sweepEvery(sweepInterval)
+	}
+	return kvs
+}
+
+// Get implements the SessionKVS interface.
+func (kvs *SessionMemoryKVS) Get(key string) ([]byte, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	entry, found := kvs.entries[key]
+	if !found {
+		return nil, nil
+	}
+	if entry.expired(util.Now()) {
+		delete(kvs.entries, key)
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+// Set implements the SessionKVS interface.
+func (kvs *SessionMemoryKVS) Set(key string, value []byte, expires time.Duration) error {
+	entry := sessionMemoryEntry{value: append([]byte(nil), value...)}
+	if expires > 0 {
+		entry.expires = util.Now().Add(expires)
+	}
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	kvs.entries[key] = entry
+	return nil
+}
+
+// Del implements the SessionKVS interface.
+func (kvs *SessionMemoryKVS) Del(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.entries, key)
+	return nil
+}
+
+// Len returns the number of the data including the expired data that hasn't
+// been swept.
+func (kvs *SessionMemoryKVS) Len() int {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	return len(kvs.entries)
+}
+
+// Sweep deletes the expired data.
+func (kvs *SessionMemoryKVS) Sweep() {
+	now := util.Now()
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	for key, entry := range kvs.entries {
+		if entry.expired(now) {
+			delete(kvs.entries, key)
+		}
+	}
+}
+
+// Close stops sweeping.
+func (kvs *SessionMemoryKVS) Close() error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	select {
+	case <-kvs.done:
+	default:
+		close(kvs.done)
+	}
+	return nil
+}
+
+func (kvs *SessionMemoryKVS) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			kvs.Sweep()
+		case <-kvs.done:
+			return
+		}
+	}
+}
+
+func (e sessionMemoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// SessionFileKVS is a file-system implementation of SessionKVS.
+// Each data is saved to a file in Dir. The expired files are deleted when
+// they are read or Sweep is called.
+// Sweep is called periodically only if SessionFileKVS is created by
+// NewSessionFileKVS with the sweep interval. Otherwise, the application must
+// call Sweep by itself to delete the expired files that are never read again.
+type SessionFileKVS struct {
+	// Directory to save the files. It will be created if not exists.
+	Dir string
+
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+// sessionFileTempPrefix is the prefix of the temporary file of SessionFileKVS.
+const sessionFileTempPrefix = ".tmp-"
+
+// sessionFileTempExpires is the age of the temporary file of SessionFileKVS
+// that is regarded as left by the failed Set, and is deleted by Sweep.
+const sessionFileTempExpires = 10 * time.Minute
+
+// NewSessionFileKVS returns a new SessionFileKVS that saves the files to dir.
+// If sweepInterval is greater than 0, the expired files will be swept at
+// every sweepInterval until Close is called.
+func NewSessionFileKVS(dir string, sweepInterval time.Duration) *SessionFileKVS {
+	kvs := &SessionFileKVS{
+		Dir:  dir,
+		done: make(chan struct{}),
+	}
+	if sweepInterval > 0 {
+		go kvs.sweepEvery(sweepInterval)
+	}
+	return kvs
+}
+
+// Get implements the SessionKVS interface.
+func (kvs *SessionFileKVS) Get(key string) ([]byte, error) {
+	path, err := kvs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	value, expired := kvs.unmarshal(buf, util.Now())
+	if expired {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return value, nil
+}
+
+// Set implements the SessionKVS interface.
+// The file is written atomically by renaming a temporary file.
+func (kvs *SessionFileKVS) Set(key string, value []byte, expires time.Duration) error {
+	path, err := kvs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(kvs.Dir, 0700); err != nil {
+		return err
+	}
+	var expiresAt int64
+	if expires > 0 {
+		expiresAt = util.Now().Add(expires).UnixNano()
+	}
+	buf := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(buf, uint64(expiresAt))
+	buf = append(buf, value...)
+	f, err := ioutil.TempFile(kvs.Dir, sessionFileTempPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Del implements the SessionKVS interface.
+func (kvs *SessionFileKVS) Del(key string) error {
+	path, err := kvs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Sweep deletes the expired files.
+// It also deletes the stale temporary files that are left by the failed Set.
+func (kvs *SessionFileKVS) Sweep() error {
+	files, err := ioutil.ReadDir(kvs.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	now := util.Now()
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(fi.Name(), sessionFileTempPrefix) {
+			if now.Sub(fi.ModTime()) < sessionFileTempExpires {
+				continue
+			}
+			if err := os.Remove(filepath.Join(kvs.Dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if !isValidSessionID(fi.Name()) {
+			continue
+		}
+		path := filepath.Join(kvs.Dir, fi.Name())
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if _, expired := kvs.unmarshal(buf, now); expired {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops sweeping.
+func (kvs *SessionFileKVS) Close() error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	if kvs.done == nil {
+		return nil
+	}
+	select {
+	case <-kvs.done:
+	default:
+		close(kvs.done)
+	}
+	return nil
+}
+
+// Validate validates Dir.
+func (kvs *SessionFileKVS) Validate() error {
+	if kvs.Dir == "" {
+		return fmt.Errorf("kocha: session: kocha.SessionFileKVS.Dir must be specified")
+	}
+	return nil
+}
+
+func (kvs *SessionFileKVS) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			kvs.Sweep()
+		case <-kvs.done:
+			return
+		}
+	}
+}
+
+func (kvs *SessionFileKVS) path(key string) (string, error) {
+	if !isValidSessionID(key) {
+		return "", fmt.Errorf("kocha: session: invalid key: %q", key)
+	}
+	return filepath.Join(kvs.Dir, key), nil
+}
+
+// unmarshal returns the value from the content of file, and whether the value
+// has been expired. The broken content is treated as expired.
+func (kvs *SessionFileKVS) unmarshal(buf []byte, now time.Time) (value []byte, expired bool) {
+	if len(buf) < 8 {
+		return nil, true
+	}
+	expiresAt := int64(binary.BigEndian.Uint64(buf[:8]))
+	if expiresAt != 0 && now.UnixNano() >= expiresAt {
+		return nil, true
+	}
+	return buf[8:], false
+}
@@ -3,12 +3,19 @@ package kocha_test
 import (
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 
 	"github.com/naoina/kocha"
+	"github.com/naoina/kocha/util"
 )
 
 func TestSession(t *testing.T) {
@@ -197,6 +204,186 @@ func Test_SessionCookieStore(t *testing.T) {
 	}()
 }
 
+func Test_SessionCookieStore_withPreviousKeys(t *testing.T) {
+	oldKeys := kocha.SessionCookieKeys{SecretKey: strings.Repeat("o", 32), SigningKey: "old signing key"}
+	oldStore := &kocha.SessionCookieStore{SecretKey: oldKeys.SecretKey, SigningKey: oldKeys.SigningKey}
+	newStore := &kocha.SessionCookieStore{
+		SecretKey:  strings.Repeat("n", 16),
+		SigningKey: "new signing key",
+		PreviousKeys: []kocha.SessionCookieKeys{
+			{SecretKey: strings.Repeat("x", 24), SigningKey: "unused signing key"},
+			oldKeys,
+		},
+	}
+	sess := kocha.Session{"name": "naoina"}
+	oldKey, err := oldStore.Save(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := newStore.Load(oldKey)
+	if err != nil {
+		t.Fatalf(`SessionCookieStore.Load(%#v) with PreviousKeys => (_, %#v); want (_, nil)`, oldKey, err)
+	}
+	if !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionCookieStore.Load(%#v) with PreviousKeys => (%#v, nil); want (%#v, nil)`, oldKey, actual, sess)
+	}
+	newKey, err := newStore.Save(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oldStore.Load(newKey); err == nil {
+		t.Errorf(`SessionCookieStore.Load(%#v) with old keys => (_, nil); want (_, error)`, newKey)
+	}
+	current := &kocha.SessionCookieStore{SecretKey: newStore.SecretKey, SigningKey: newStore.SigningKey}
+	if actual, err := current.Load(newKey); err != nil || !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionCookieStore.Load(%#v) => (%#v, %#v); want (%#v, nil)`, newKey, actual, err, sess)
+	}
+	_, err = current.Load(oldKey)
+	expect := fmt.Errorf("kocha: session cookie verification failed")
+	if !reflect.DeepEqual(err, expect) {
+		t.Errorf(`SessionCookieStore.Load(%#v) without PreviousKeys => (_, %#v); want (_, %#v)`, oldKey, err, expect)
+	}
+
+	// the session cookie saved with the old keys is re-issued with the current keys.
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	appStore := &kocha.SessionCookieStore{
+		SecretKey:    encode(newStore.SecretKey),
+		SigningKey:   encode(newStore.SigningKey),
+		PreviousKeys: []kocha.SessionCookieKeys{{SecretKey: encode(oldKeys.SecretKey), SigningKey: encode(oldKeys.SigningKey)}},
+	}
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: appStore, SessionExpires: time.Hour},
+		&kocha.DispatchMiddleware{},
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			return c.RenderText(c.Session.Get("name"))
+		}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Set("_kocha._sess._expires", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+	if oldKey, err = oldStore.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "test_session", Value: oldKey})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if actual, expect := w.Body.String(), "naoina"; actual != expect {
+		t.Errorf(`GET / with old session cookie => %#v; want %#v`, actual, expect)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf(`GET /; cookies => %#v; want 1 cookie`, cookies)
+	}
+	if actual, err := current.Load(cookies[0].Value); err != nil || actual.Get("name") != "naoina" {
+		t.Errorf(`SessionCookieStore.Load(re-issued cookie) => (%#v, %#v); want session of "naoina"`, actual, err)
+	}
+}
+
+func Test_SessionCookieStore_withKey(t *testing.T) {
+	legacyStore := &kocha.SessionCookieStore{SecretKey: strings.Repeat("s", 32), SigningKey: "signing key"}
+	store := &kocha.SessionCookieStore{
+		Key:        strings.Repeat("k", 32),
+		SecretKey:  legacyStore.SecretKey,
+		SigningKey: legacyStore.SigningKey,
+		CookieName: "test_session",
+	}
+	sess := kocha.Session{"name": "naoina"}
+	key, err := store.Save(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := store.Load(key); err != nil || !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionCookieStore.Load(%#v) => (%#v, %#v); want (%#v, nil)`, key, actual, err, sess)
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := decoded[0], byte(1); actual != expect {
+		t.Errorf(`SessionCookieStore.Save(%#v); version byte => %#v; want %#v`, sess, actual, expect)
+	}
+	if _, err := legacyStore.Load(key); err == nil {
+		t.Errorf(`SessionCookieStore.Load(%#v) without Key => (_, nil); want (_, error)`, key)
+	}
+
+	// the cookie name is bound to the session cookie.
+	other := &kocha.SessionCookieStore{Key: store.Key, CookieName: "other_session"}
+	_, err = other.Load(key)
+	expect := fmt.Errorf("kocha: session cookie verification failed")
+	if !reflect.DeepEqual(err, expect) {
+		t.Errorf(`SessionCookieStore.Load(%#v) with other CookieName => (_, %#v); want (_, %#v)`, key, err, expect)
+	}
+
+	// tampered session cookie.
+	tampered := append([]byte(nil), decoded...)
+	tampered[len(tampered)-1] ^= 1
+	tamperedKey := base64.RawURLEncoding.EncodeToString(tampered)
+	if _, err := store.Load(tamperedKey); err == nil {
+		t.Errorf(`SessionCookieStore.Load(%#v) => (_, nil); want (_, error)`, tamperedKey)
+	}
+
+	// the session cookie saved in the legacy format is still loaded.
+	legacyKey, err := legacyStore.Save(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := store.Load(legacyKey); err != nil || !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionCookieStore.Load(%#v) => (%#v, %#v); want (%#v, nil)`, legacyKey, actual, err, sess)
+	}
+	aeadOnly := &kocha.SessionCookieStore{Key: store.Key, CookieName: store.CookieName}
+	if _, err := aeadOnly.Load(legacyKey); err == nil {
+		t.Errorf(`SessionCookieStore.Load(%#v) without SecretKey => (_, nil); want (_, error)`, legacyKey)
+	}
+
+	// key rotation.
+	rotated := &kocha.SessionCookieStore{
+		Key:          strings.Repeat("n", 16),
+		PreviousKeys: []kocha.SessionCookieKeys{{Key: store.Key}},
+		CookieName:   store.CookieName,
+	}
+	if actual, err := rotated.Load(key); err != nil || !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionCookieStore.Load(%#v) with PreviousKeys => (%#v, %#v); want (%#v, nil)`, key, actual, err, sess)
+	}
+	rotatedKey, err := rotated.Save(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aeadOnly.Load(rotatedKey); err == nil {
+		t.Errorf(`SessionCookieStore.Load(%#v) with old Key => (_, nil); want (_, error)`, rotatedKey)
+	}
+
+	// SessionMiddleware binds its Name to the session cookie.
+	appStore := &kocha.SessionCookieStore{Key: base64.StdEncoding.EncodeToString([]byte(store.Key))}
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: appStore, SessionExpires: time.Hour},
+		&kocha.DispatchMiddleware{},
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			c.Session.Set("name", "naoina")
+			return c.RenderText("")
+		}},
+	}
+	if _, err := kocha.New(config); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := appStore.CookieName, "test_session"; actual != expect {
+		t.Errorf(`kocha.New(config); SessionCookieStore.CookieName => %#v; want %#v`, actual, expect)
+	}
+}
+
 func Test_SessionCookieStore_Validate(t *testing.T) {
 	// tests for validate the key size.
 	for _, keySize := range []int{16, 24, 32} {
@@ -208,6 +395,52 @@ func Test_SessionCookieStore_Validate(t *testing.T) {
 			t.Errorf("Expect key size %v is valid, but returned error: %v", keySize, err)
 		}
 	}
+	// tests for validate the previous keys.
+	store := &kocha.SessionCookieStore{
+		SecretKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 16))),
+		SigningKey: base64.StdEncoding.EncodeToString([]byte("a")),
+		PreviousKeys: []kocha.SessionCookieKeys{
+			{
+				SecretKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32))),
+				SigningKey: base64.StdEncoding.EncodeToString([]byte("b")),
+			},
+			{
+				SecretKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("c", 20))),
+				SigningKey: base64.StdEncoding.EncodeToString([]byte("c")),
+			},
+		},
+	}
+	actual := store.Validate()
+	expect := fmt.Errorf("kocha: session: kocha.SessionCookieStore.PreviousKeys[1].SecretKey size must be 16, 24 or 32, but 20")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SessionCookieStore.Validate() => %#v; want %#v`, actual, expect)
+	}
+	if actual, expect := store.PreviousKeys[0].SecretKey, strings.Repeat("b", 32); actual != expect {
+		t.Errorf(`SessionCookieStore.Validate(); PreviousKeys[0].SecretKey => %#v; want %#v`, actual, expect)
+	}
+	// tests for validate the key of AEAD.
+	for _, v := range []struct {
+		store  *kocha.SessionCookieStore
+		expect error
+	}{
+		{&kocha.SessionCookieStore{Key: base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))}, nil},
+		{&kocha.SessionCookieStore{Key: base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 20)))}, fmt.Errorf("kocha: session: kocha.SessionCookieStore.Key size must be 16, 24 or 32, but 20")},
+		{&kocha.SessionCookieStore{
+			Key:        base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 16))),
+			SecretKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 20))),
+			SigningKey: base64.StdEncoding.EncodeToString([]byte("b")),
+		}, fmt.Errorf("kocha: session: kocha.SessionCookieStore.SecretKey size must be 16, 24 or 32, but 20")},
+		{&kocha.SessionCookieStore{
+			Key:          base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 16))),
+			PreviousKeys: []kocha.SessionCookieKeys{{Key: base64.StdEncoding.EncodeToString([]byte(strings.Repeat("c", 8)))}},
+		}, fmt.Errorf("kocha: session: kocha.SessionCookieStore.PreviousKeys[0].Key size must be 16, 24 or 32, but 8")},
+	} {
+		actual := v.store.Validate()
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SessionCookieStore.Validate() => %#v; want %#v`, actual, expect)
+		}
+	}
 	// boundary tests
 	for _, keySize := range []int{15, 17, 23, 25, 31, 33} {
 		store := &kocha.SessionCookieStore{
@@ -219,3 +452,393 @@ func Test_SessionCookieStore_Validate(t *testing.T) {
 		}
 	}
 }
+
+func testSessionKVSStore(t *testing.T, kvs kocha.SessionKVS) {
+	store := &kocha.SessionKVSStore{KVS: kvs, Expires: time.Hour}
+	if err := store.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sess := kocha.Session{"name": "naoina"}
+	id, err := store.Save(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) < 43 || strings.Contains(id, "naoina") {
+		t.Errorf(`SessionKVSStore.Save(%#v) => (%#v, nil); want random session ID`, sess, id)
+	}
+	actual, err := store.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sess) {
+		t.Errorf(`SessionKVSStore.Load(%#v) => (%#v, nil); want (%#v, nil)`, id, actual, sess)
+	}
+	actual.Set("age", "17")
+	id2, err := store.Save(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id2 != id {
+		t.Errorf(`SessionKVSStore.Save(%#v) => (%#v, nil); want (%#v, nil)`, actual, id2, id)
+	}
+	loaded, err := store.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, actual) {
+		t.Errorf(`SessionKVSStore.Load(%#v) => (%#v, nil); want (%#v, nil)`, id, loaded, actual)
+	}
+	for _, v := range []struct {
+		key    string
+		expect error
+	}{
+		{"unknownsessionid", kocha.NewErrSession("session not found")},
+		{"../" + id, kocha.NewErrSession("invalid session ID")},
+		{"", kocha.NewErrSession("invalid session ID")},
+	} {
+		_, err := store.Load(v.key)
+		if !reflect.DeepEqual(err, v.expect) {
+			t.Errorf(`SessionKVSStore.Load(%#v) => (_, %#v); want (_, %#v)`, v.key, err, v.expect)
+		}
+	}
+}
+
+func Test_SessionKVSStore(t *testing.T) {
+	kvs := kocha.NewSessionMemoryKVS(0)
+	defer kvs.Close()
+	testSessionKVSStore(t, kvs)
+
+	dir, err := ioutil.TempDir("", "Test_SessionKVSStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testSessionKVSStore(t, &kocha.SessionFileKVS{Dir: filepath.Join(dir, "sessions")})
+
+	store := &kocha.SessionKVSStore{}
+	actual := store.Validate()
+	expect := fmt.Errorf("kocha: session: kocha.SessionKVSStore.KVS must be specified")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SessionKVSStore.Validate() => %#v; want %#v`, actual, expect)
+	}
+	store = &kocha.SessionKVSStore{KVS: &kocha.SessionFileKVS{}}
+	actual = store.Validate()
+	expect = fmt.Errorf("kocha: session: kocha.SessionFileKVS.Dir must be specified")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SessionKVSStore.Validate() => %#v; want %#v`, actual, expect)
+	}
+	store = &kocha.SessionKVSStore{KVS: kvs}
+	actual = store.Validate()
+	expect = fmt.Errorf("kocha: session: kocha.SessionKVSStore.Expires or SessionMiddleware.SessionExpires must be positive")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SessionKVSStore.Validate() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func testSessionKVSExpires(t *testing.T, kvs kocha.SessionKVS, sweep func() error) {
+	origNow := util.Now
+	now := time.Unix(1383820443, 0)
+	util.Now = func() time.Time { return now }
+	defer func() {
+		util.Now = origNow
+	}()
+	for key, expires := range map[string]time.Duration{"short": time.Minute, "long": time.Hour, "persistent": 0} {
+		if err := kvs.Set(key, []byte(key), expires); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Minute)
+	if err := sweep(); err != nil {
+		t.Fatal(err)
+	}
+	for key, expect := range map[string][]byte{"short": nil, "long": []byte("long"), "persistent": []byte("persistent")} {
+		actual, err := kvs.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%T.Get(%#v) => (%#v, nil); want (%#v, nil)`, kvs, key, actual, expect)
+		}
+	}
+	now = now.Add(time.Hour)
+	actual, err := kvs.Get("long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != nil {
+		t.Errorf(`%T.Get(%#v) => (%#v, nil); want (nil, nil)`, kvs, "long", actual)
+	}
+	if err := kvs.Del("persistent"); err != nil {
+		t.Fatal(err)
+	}
+	actual, err = kvs.Get("persistent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != nil {
+		t.Errorf(`%T.Get(%#v) after Del => (%#v, nil); want (nil, nil)`, kvs, "persistent", actual)
+	}
+}
+
+func Test_SessionMemoryKVS(t *testing.T) {
+	kvs := kocha.NewSessionMemoryKVS(0)
+	defer kvs.Close()
+	testSessionKVSExpires(t, kvs, func() error {
+		kvs.Sweep()
+		if actual, expect := kvs.Len(), 2; actual != expect {
+			t.Errorf(`SessionMemoryKVS.Len() after Sweep => %#v; want %#v`, actual, expect)
+		}
+		return nil
+	})
+}
+
+func Test_SessionFileKVS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "Test_SessionFileKVS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kvs := &kocha.SessionFileKVS{Dir: dir}
+	testSessionKVSExpires(t, kvs, func() error {
+		if err := kvs.Sweep(); err != nil {
+			return err
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		var actual []string
+		for _, fi := range files {
+			actual = append(actual, fi.Name())
+		}
+		expect := []string{"long", "persistent"}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`files after SessionFileKVS.Sweep() => %#v; want %#v`, actual, expect)
+		}
+		return nil
+	})
+	for _, key := range []string{"../session", "a/b", ""} {
+		if err := kvs.Set(key, []byte("value"), 0); err == nil {
+			t.Errorf(`SessionFileKVS.Set(%#v, ...) => nil; want error`, key)
+		}
+	}
+
+	// the stale temporary files are swept.
+	for _, name := range []string{".tmp-stale", ".tmp-fresh"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("broken"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, ".tmp-stale"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	for name, expect := range map[string]bool{".tmp-stale": false, ".tmp-fresh": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if actual := err == nil; actual != expect {
+			t.Errorf(`SessionFileKVS.Sweep(); %#v exists => %#v; want %#v`, name, actual, expect)
+		}
+	}
+}
+
+func Test_NewSessionFileKVS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "Test_NewSessionFileKVS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kvs := kocha.NewSessionFileKVS(dir, 10*time.Millisecond)
+	defer kvs.Close()
+	if err := kvs.Set("expired", []byte("value"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "expired")
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf(`NewSessionFileKVS(%#v, 10ms); %#v exists after sweep interval; want not exists`, dir, path)
+}
+
+func TestSessionMiddleware_withSessionKVSStore(t *testing.T) {
+	kvs := kocha.NewSessionMemoryKVS(0)
+	defer kvs.Close()
+	store := &kocha.SessionKVSStore{KVS: kvs}
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: store, SessionExpires: time.Hour},
+		&kocha.DispatchMiddleware{},
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			count := c.Session.Get("count") + "+"
+			c.Session.Set("count", count)
+			return c.RenderText(count)
+		}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := store.Expires, time.Hour; actual != expect {
+		t.Errorf(`SessionKVSStore.Expires => %#v; want %#v`, actual, expect)
+	}
+	var cookie *http.Cookie
+	for _, expect := range []string{"+", "++", "+++"} {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		actual := w.Body.String()
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`GET / => %#v; want %#v`, actual, expect)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf(`GET /; cookies => %#v; want 1 cookie`, cookies)
+		}
+		if cookie != nil && cookies[0].Value != cookie.Value {
+			t.Errorf(`GET /; session ID => %#v; want %#v`, cookies[0].Value, cookie.Value)
+		}
+		cookie = cookies[0]
+	}
+	if actual, expect := kvs.Len(), 1; actual != expect {
+		t.Errorf(`SessionMemoryKVS.Len() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestSessionMiddleware_withNewEmptySession(t *testing.T) {
+	kvs := kocha.NewSessionMemoryKVS(0)
+	defer kvs.Close()
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: &kocha.SessionKVSStore{KVS: kvs}, SessionExpires: time.Hour},
+		&kocha.DispatchMiddleware{},
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			return c.RenderText("root")
+		}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf(`GET /; cookies => %#v; want no cookies`, cookies)
+		}
+	}
+	if actual, expect := kvs.Len(), 0; actual != expect {
+		t.Errorf(`SessionMemoryKVS.Len() => %#v; want %#v`, actual, expect)
+	}
+
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: &kocha.SessionKVSStore{KVS: kvs}},
+	}
+	_, err = kocha.New(config)
+	var actual interface{} = err
+	var expect interface{} = fmt.Errorf("kocha: session: kocha.SessionKVSStore.Expires or SessionMiddleware.SessionExpires must be positive")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`kocha.New(config) with SessionExpires 0 => (_, %#v); want (_, %#v)`, actual, expect)
+	}
+}
+
+func TestContext_RegenerateSession_and_DestroySession(t *testing.T) {
+	kvs := kocha.NewSessionMemoryKVS(0)
+	defer kvs.Close()
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{
+		&kocha.SessionMiddleware{Name: "test_session", Store: &kocha.SessionKVSStore{KVS: kvs}, SessionExpires: time.Hour},
+		&kocha.DispatchMiddleware{},
+	}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "GET", Handler: func(c *kocha.Context) error {
+			c.Session.Set("user", c.Session.Get("user")+"+")
+			return c.RenderText(c.Session.Get("user"))
+		}},
+		{Name: "login", Path: "/login", Method: "GET", Handler: func(c *kocha.Context) error {
+			c.RegenerateSession()
+			return c.RenderText(c.Session.Get("user"))
+		}},
+		{Name: "logout", Path: "/logout", Method: "GET", Handler: func(c *kocha.Context) error {
+			c.DestroySession()
+			c.Session.Set("user", "ignored")
+			return c.RenderText(c.Session.Get("user"))
+		}},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(path string, cookie *http.Cookie) (string, *http.Cookie) {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf(`GET %v; cookies => %#v; want 1 cookie`, path, cookies)
+		}
+		return w.Body.String(), cookies[0]
+	}
+
+	body, cookie := request("/", nil)
+	if expect := "+"; body != expect {
+		t.Errorf(`GET / => %#v; want %#v`, body, expect)
+	}
+	oldCookie := cookie
+	body, cookie = request("/login", cookie)
+	if expect := "+"; body != expect {
+		t.Errorf(`GET /login => %#v; want %#v`, body, expect)
+	}
+	if cookie.Value == oldCookie.Value {
+		t.Errorf(`GET /login; session ID => %#v; want new session ID`, cookie.Value)
+	}
+	body, _ = request("/", oldCookie)
+	if expect := "+"; body != expect {
+		t.Errorf(`GET / with old session ID => %#v; want %#v`, body, expect)
+	}
+	body, cookie = request("/", cookie)
+	if expect := "++"; body != expect {
+		t.Errorf(`GET / with new session ID => %#v; want %#v`, body, expect)
+	}
+	sessionCookie := cookie
+	body, cookie = request("/logout", cookie)
+	var actual interface{} = []interface{}{body, cookie.Value, cookie.MaxAge}
+	var expect interface{} = []interface{}{"ignored", "", -1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`GET /logout => %#v; want %#v`, actual, expect)
+	}
+	body, _ = request("/", sessionCookie)
+	if expect := "+"; body != expect {
+		t.Errorf(`GET / with destroyed session ID => %#v; want %#v`, body, expect)
+	}
+	if actual, expect := kvs.Len(), 2; actual != expect {
+		t.Errorf(`SessionMemoryKVS.Len() => %#v; want %#v`, actual, expect)
+	}
+}
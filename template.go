@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -104,6 +105,8 @@ func (t *Template) buildFuncMap() (*Template, error) {
 		"invoke_template": t.invokeTemplate,
 		"flash":           t.flash,
 		"join":            t.join,
+		"query":           t.query,
+		"fragment":        t.fragment,
 	}
 	for name, fn := range t.FuncMap {
 		m[name] = fn
@@ -248,10 +251,34 @@ func (t *Template) in(a, b interface{}) (bool, error) {
 }
 
 // url is for "url" template function.
+// The query and the fragment can be given by "query" and "fragment" template
+// functions. e.g. {{url "search" (query "q" "go" "page" 2) (fragment "top")}}
 func (t *Template) url(name string, v ...interface{}) (string, error) {
 	return t.app.Router.Reverse(name, v...)
 }
 
+// query is for "query" template function.
+// It returns url.Values that is built from pairs of key and value.
+func (t *Template) query(pairs ...interface{}) (url.Values, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("number of arguments must be even, got %d", len(pairs))
+	}
+	query := make(url.Values, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("type of key must be string, got `%T'", pairs[i])
+		}
+		query.Add(key, fmt.Sprint(pairs[i+1]))
+	}
+	return query, nil
+}
+
+// fragment is for "fragment" template function.
+func (t *Template) fragment(s string) Fragment {
+	return Fragment(s)
+}
+
 // nl2br is for "nl2br" template function.
 func (t *Template) nl2br(text string) template.HTML {
 	return template.HTML(strings.Replace(template.HTMLEscapeString(text), "\n", "<br>", -1))
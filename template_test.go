@@ -84,6 +84,37 @@ func TestTemplate_FuncMap_url(t *testing.T) {
 	}()
 }
 
+func TestTemplate_FuncMap_url_withQueryAndFragment(t *testing.T) {
+	app := kocha.NewTestApp()
+	funcMap := template.FuncMap(app.Template.FuncMap)
+	for _, v := range []struct {
+		tmpl   string
+		expect string
+	}{
+		{`{{url "root" (query "q" "go" "page" 2)}}`, "/?page=2&amp;q=go"},
+		{`{{url "user" 713 (fragment "top")}}`, "/user/713#top"},
+		{`{{url "user" 713 (query "tab" "posts") (fragment "top")}}`, "/user/713?tab=posts#top"},
+	} {
+		tmpl := template.Must(template.New("test").Funcs(funcMap).Parse(v.tmpl))
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, nil); err != nil {
+			t.Errorf("%v: %v", v.tmpl, err)
+			continue
+		}
+		actual := buf.String()
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("%v => %#v; want %#v", v.tmpl, actual, expect)
+		}
+	}
+
+	tmpl := template.Must(template.New("test").Funcs(funcMap).Parse(`{{url "root" (query "q")}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err == nil {
+		t.Errorf("query with odd number of arguments => nil; want error")
+	}
+}
+
 func TestTemplate_FuncMap_nl2br(t *testing.T) {
 	app := kocha.NewTestApp()
 	funcMap := template.FuncMap(app.Template.FuncMap)
@@ -0,0 +1,183 @@
+package kocha
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// files returns the uploaded files of the multipart form.
+func (params *Params) files() map[string][]*multipart.FileHeader {
+	if params.c == nil || params.c.Request == nil || params.c.Request.MultipartForm == nil {
+		return nil
+	}
+	return params.c.Request.MultipartForm.File
+}
+
+// bindFiles binds the uploaded files of key to v.
+// v must be a *multipart.FileHeader or []*multipart.FileHeader.
+func (params *Params) bindFiles(key string, v reflect.Value) (empty, ok bool, err error) {
+	files := params.files()[key]
+	if len(files) == 0 {
+		return true, true, nil
+	}
+	if v.Type() == fileHeaderType {
+		v.Set(reflect.ValueOf(files[0]))
+	} else {
+		v.Set(reflect.ValueOf(append([]*multipart.FileHeader(nil), files...)))
+	}
+	return false, true, nil
+}
+
+// validateFiles validates the uploaded files of v by the rule.
+func (r *validationRule) validateFiles(v reflect.Value) (*ValidationError, error) {
+	var files []*multipart.FileHeader
+	switch {
+	case v.CanAddr() && v.Addr().Type() == fileHeaderType:
+		files = append(files, v.Addr().Interface().(*multipart.FileHeader))
+	case v.Type() == fileHeadersType:
+		files = v.Interface().([]*multipart.FileHeader)
+	default:
+		return nil, fmt.Errorf("rule %v cannot be applied to %v", r.name, v.Type())
+	}
+	for _, fh := range files {
+		switch r.name {
+		case "maxsize":
+			if float64(fh.Size) > r.n {
+				return r.error("larger than %v", r.param), nil
+			}
+		case "mimetype":
+			mimeType, err := DetectFileMimeType(fh)
+			if err != nil {
+				return nil, err
+			}
+			if !matchMimeType(mimeType, r.mimeTypes) {
+				return r.error("not a file of %v", strings.Join(r.mimeTypes, " or ")), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// DetectFileMimeType returns the MIME type of the uploaded file.
+// The MIME type is sniffed from the content of the file by
+// http.DetectContentType, and Content-Type header of the file is not trusted.
+func DetectFileMimeType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	mimeType := http.DetectContentType(buf[:n])
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return mimeType, nil
+}
+
+func matchMimeType(mimeType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == mimeType || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFileSize parses the size that can have a suffix of KB, MB or GB.
+func parseFileSize(s string) (int64, error) {
+	unit := int64(1)
+	for suffix, u := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(s, suffix) {
+			s, unit = strings.TrimSuffix(s, suffix), u
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return n * unit, nil
+}
+
+// SaveUploadedFile saves the uploaded file into dir, and returns the path of
+// the saved file.
+// The file name is the base name of the uploaded file name that is removed
+// the path separators, and it never overwrites an existing file. If the file
+// of the same name exists, a number is added to the file name such as
+// "photo-1.jpg".
+func SaveUploadedFile(fh *multipart.FileHeader, dir string) (path string, err error) {
+	name := uploadedFileName(fh.Filename)
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	var dst *os.File
+	for i := 0; ; i++ {
+		path = filepath.Join(dir, name)
+		if i > 0 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+		}
+		dst, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// uploadedFileName returns the safe file name from the file name of the
+// uploaded file.
+func uploadedFileName(filename string) string {
+	filename = strings.Replace(filename, "\\", "/", -1)
+	if i := strings.LastIndex(filename, "/"); i >= 0 {
+		filename = filename[i+1:]
+	}
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, filename)
+	if strings.Trim(filename, ". ") == "" {
+		return "upload"
+	}
+	if strings.HasPrefix(filename, ".") {
+		filename = "_" + filename[1:]
+	}
+	return filename
+}
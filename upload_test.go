@@ -0,0 +1,206 @@
+package kocha_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/kocha"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type testUploadFile struct {
+	name    string
+	content []byte
+}
+
+func newTestUploadRequest(t *testing.T, fields map[string]string, files map[string][]testUploadFile) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, fs := range files {
+		for _, f := range fs {
+			fw, err := w.CreateFormFile(name, f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fw.Write(f.content); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func serveTestUpload(t *testing.T, req *http.Request, handler func(c *kocha.Context) error) {
+	config := newConfig()
+	config.Logger = &kocha.LoggerConfig{Writer: ioutil.Discard}
+	config.Middlewares = []kocha.Middleware{&kocha.FormMiddleware{}, &kocha.DispatchMiddleware{}}
+	config.RouteTable = kocha.RouteTable{
+		{Name: "root", Path: "/", Method: "POST", Handler: handler},
+	}
+	app, err := kocha.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestParams_Bind_withFiles(t *testing.T) {
+	type Profile struct {
+		Name   string
+		Avatar *multipart.FileHeader   `validate:"required,maxsize=1KB,mimetype=image/png|image/gif"`
+		Photos []*multipart.FileHeader `validate:"max=2,mimetype=image/*"`
+		Resume *multipart.FileHeader   `validate:"maxsize=8"`
+	}
+	for _, v := range []struct {
+		files       map[string][]testUploadFile
+		expectFiles map[string][]string
+		expectErrs  map[string][]string
+	}{
+		{map[string][]testUploadFile{
+			"profile.avatar": {{"avatar.png", testPNG}},
+			"profile.photos": {{"1.png", testPNG}, {"2.png", testPNG}},
+			"profile.resume": {{"resume.txt", []byte("resume")}},
+		}, map[string][]string{
+			"avatar": {"avatar.png"},
+			"photos": {"1.png", "2.png"},
+			"resume": {"resume.txt"},
+		}, map[string][]string{}},
+		{map[string][]testUploadFile{
+			"profile.photos": {{"1.png", testPNG}, {"2.txt", []byte("text")}},
+			"profile.resume": {{"resume.txt", []byte("long resume")}},
+		}, map[string][]string{
+			"photos": {"1.png", "2.txt"},
+			"resume": {"resume.txt"},
+		}, map[string][]string{
+			"avatar": {"avatar is required"},
+			"photos": {"photos is not a file of image/*"},
+			"resume": {"resume is larger than 8"},
+		}},
+		{map[string][]testUploadFile{
+			"profile.avatar": {{"avatar.png", append(append([]byte{}, testPNG...), make([]byte, 1024)...)}},
+			"profile.photos": {{"1.png", testPNG}, {"2.png", testPNG}, {"3.png", testPNG}},
+		}, map[string][]string{
+			"avatar": {"avatar.png"},
+			"photos": {"1.png", "2.png", "3.png"},
+		}, map[string][]string{
+			"avatar": {"avatar is larger than 1KB"},
+			"photos": {"photos is longer than 2 elements"},
+		}},
+		{map[string][]testUploadFile{
+			"profile.avatar": {{"avatar.png", []byte("<html><body></body></html>")}},
+		}, map[string][]string{
+			"avatar": {"avatar.png"},
+		}, map[string][]string{
+			"avatar": {"avatar is not a file of image/png or image/gif"},
+		}},
+	} {
+		profile := &Profile{}
+		var bindErr error
+		errs := map[string][]string{}
+		req := newTestUploadRequest(t, map[string]string{"profile.name": "naoina"}, v.files)
+		serveTestUpload(t, req, func(c *kocha.Context) error {
+			bindErr = c.Params.From("profile").Bind(profile, "name", "avatar", "photos", "resume")
+			for name, es := range c.Errors {
+				for _, e := range es {
+					errs[name] = append(errs[name], e.Error())
+				}
+			}
+			return c.RenderText("")
+		})
+		if bindErr != nil {
+			t.Errorf("Bind(%#v) => %#v; want nil", profile, bindErr)
+		}
+		actualFiles := map[string][]string{}
+		if profile.Avatar != nil {
+			actualFiles["avatar"] = []string{profile.Avatar.Filename}
+		}
+		for _, fh := range profile.Photos {
+			actualFiles["photos"] = append(actualFiles["photos"], fh.Filename)
+		}
+		if profile.Resume != nil {
+			actualFiles["resume"] = []string{profile.Resume.Filename}
+		}
+		var actual interface{} = []interface{}{profile.Name, actualFiles}
+		var expect interface{} = []interface{}{"naoina", v.expectFiles}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v) => %#v; want %#v", profile, actual, expect)
+		}
+		actual = errs
+		expect = v.expectErrs
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Bind(%#v); Errors => %#v; want %#v", profile, actual, expect)
+		}
+	}
+}
+
+func TestSaveUploadedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSaveUploadedFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, v := range []struct {
+		filename string
+		expect   string
+	}{
+		{"photo.png", "photo.png"},
+		{"photo.png", "photo-1.png"},
+		{"../../photo.png", "photo-2.png"},
+		{`C:\Users\naoina\report.pdf`, "report.pdf"},
+		{".htaccess", "_htaccess"},
+		{"..", "upload"},
+	} {
+		var path string
+		var saveErr error
+		req := newTestUploadRequest(t, nil, map[string][]testUploadFile{
+			"file": {{v.filename, []byte("content of " + v.filename)}},
+		})
+		serveTestUpload(t, req, func(c *kocha.Context) error {
+			_, fh, err := c.Request.FormFile("file")
+			if err != nil {
+				return err
+			}
+			path, saveErr = kocha.SaveUploadedFile(fh, dir)
+			return c.RenderText("")
+		})
+		if saveErr != nil {
+			t.Errorf("SaveUploadedFile(%q) => (_, %#v); want (_, nil)", v.filename, saveErr)
+			continue
+		}
+		var actual interface{} = path
+		var expect interface{} = filepath.Join(dir, v.expect)
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("SaveUploadedFile(%q) => (%#v, nil); want (%#v, nil)", v.filename, actual, expect)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = string(b)
+		expect = "content of " + v.filename
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("SaveUploadedFile(%q); content => %#v; want %#v", v.filename, actual, expect)
+		}
+	}
+}